@@ -0,0 +1,99 @@
+package main
+
+import (
+	"github.com/nsf/termbox-go"
+	"sort"
+	"time"
+)
+
+/*
+Agenda shows tasks of the file grouped by due date. Group headers
+are kept in view as negative numbers: -1 is the first group etc.
+Tasks are edited and toggled in place in the file.
+*/
+
+var agendaGroups = []string{
+	"Overdue", "Today", "Tomorrow", "This week", "Later", "No date",
+}
+
+// Agenda clock. Replaced in tests
+var now = time.Now
+
+func (tb *TaskBox) EnterAgendaMode() {
+	tb.mode = modeAgenda
+	tb.ClearSelection()
+	tb.cursor = 0
+	tb.scroll = 0
+	tb.calculate()
+	tb.skipHeader(1)
+}
+
+func (tb *TaskBox) HandleAgendaEvent(ev termbox.Event) {
+	tb.HandleKey(ev)
+}
+
+func agendaGroup(due, today time.Time) int {
+	if due.IsZero() {
+		return 5
+	}
+	// Week starts on Monday
+	weekLeft := (7 - int(today.Weekday()) + 1) % 7
+	if weekLeft == 0 {
+		weekLeft = 7
+	}
+	switch {
+	case due.Before(today):
+		return 0
+	case due.Before(today.AddDate(0, 0, 1)):
+		return 1
+	case due.Before(today.AddDate(0, 0, 2)):
+		return 2
+	case due.Before(today.AddDate(0, 0, weekLeft)):
+		return 3
+	}
+	return 4
+}
+
+func (tb *TaskBox) agendaView() {
+	today := today()
+	groups := make([][]int, len(agendaGroups))
+	tasks := make(map[int]Task)
+	for i, s := range tb.Lines {
+		if lineTypeOf(s) == lineTask && tb.inFilter(s) {
+			task := ParseTask(s)
+			tasks[i] = task
+			g := agendaGroup(task.Due, today)
+			groups[g] = append(groups[g], i)
+		}
+	}
+	tb.view = make([]int, 0)
+	for g, indexes := range groups {
+		if len(indexes) == 0 {
+			continue
+		}
+		sort.SliceStable(indexes, func(a, b int) bool {
+			x, y := tasks[indexes[a]], tasks[indexes[b]]
+			if !x.Due.Equal(y.Due) {
+				return x.Due.Before(y.Due)
+			}
+			return x.Priority > y.Priority
+		})
+		tb.view = append(tb.view, -(g + 1))
+		tb.view = append(tb.view, indexes...)
+	}
+}
+
+// Move cursor from group header in direction dir (1 or -1)
+// or in opposite direction if there are no tasks in dir
+func (tb *TaskBox) skipHeader(dir int) {
+	for _, d := range []int{dir, -dir} {
+		pos := tb.cursor
+		for pos >= 0 && pos < len(tb.view) && tb.view[pos] < 0 {
+			pos += d
+		}
+		if pos >= 0 && pos < len(tb.view) {
+			tb.cursor = pos
+			return
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"github.com/MakeNowJust/heredoc"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func AgendaFixture() *TaskBox {
+	// Wednesday
+	now = func() time.Time { return time.Date(2026, 10, 21, 15, 0, 0, 0, time.Local) }
+	tb := &TaskBox{Lines: []string{
+		"# Tasks",
+		"- [ ] no date",
+		"- [ ] sunday due:2026-10-25",
+		"- [ ] monday due:2026-10-26",
+		"- [ ] late due:2026-10-01",
+		"- [ ] saturday due:2026-10-24",
+		"- [ ] tomorrow due:2026-10-22",
+		"- [ ] today due:2026-10-21",
+		"- [ ] today too !! due:2026-10-21",
+		"<!-- - [ ] archived due:2026-10-21 -->",
+	}}
+	tb.calculate()
+	tb.h = 20
+	tb.EnterAgendaMode()
+	return tb
+}
+
+func TestAgendaView(t *testing.T) {
+	tb := AgendaFixture()
+	defer func() { now = time.Now }()
+	assert.Equal(t, heredoc.Doc(`
+		  Overdue:
+		> - [ ] late due:2026-10-01
+		  Today:
+		  - [ ] today too !! due:2026-10-21
+		  - [ ] today due:2026-10-21
+		  Tomorrow:
+		  - [ ] tomorrow due:2026-10-22
+		  This week:
+		  - [ ] saturday due:2026-10-24
+		  - [ ] sunday due:2026-10-25
+		  Later:
+		  - [ ] monday due:2026-10-26
+		  No date:
+		  - [ ] no date
+	`), tb.String())
+
+	// Headers are skipped
+	tb.CursorDown()
+	i, _ := tb.SelectedLine()
+	assert.Equal(t, 8, i)
+	tb.CursorUp()
+	tb.CursorUp()
+	assert.Equal(t, 1, tb.cursor)
+
+	tb.cursor = 0
+	i, _ = tb.SelectedLine()
+	assert.Equal(t, -1, i)
+	assert.Empty(t, tb.Selection())
+}
+
+func TestAgendaToggle(t *testing.T) {
+	tb := AgendaFixture()
+	defer func() { now = time.Now }()
+	tb.CursorDown()
+	tb.CursorDown()
+	tb.ChangePriority(1)
+	assert.Equal(t, "- [ ] today due:2026-10-21 !", tb.Lines[7])
+	tb.ChangePriority(1)
+	tb.ChangePriority(1)
+	// Cursor follows the line
+	i, _ := tb.SelectedLine()
+	assert.Equal(t, 7, i)
+	assert.Equal(t, 3, tb.cursor)
+
+	tb.ToggleTask()
+	assert.Equal(t, "- [x] today due:2026-10-21 !!!", tb.Lines[7])
+
+	tb.EnterTaskMode()
+	assert.Equal(t, modeTask, tb.mode)
+	i, _ = tb.SelectedLine()
+	assert.Equal(t, 7, i)
+}
+
+func TestAgendaGroup(t *testing.T) {
+	// Sunday
+	today := time.Date(2026, 10, 25, 0, 0, 0, 0, time.Local)
+	day := func(d int) time.Time { return today.AddDate(0, 0, d) }
+	assert.Equal(t, 0, agendaGroup(day(-1), today))
+	assert.Equal(t, 1, agendaGroup(day(0), today))
+	assert.Equal(t, 2, agendaGroup(day(1), today))
+	assert.Equal(t, 4, agendaGroup(day(2), today))
+	assert.Equal(t, 5, agendaGroup(time.Time{}, today))
+}
@@ -0,0 +1,350 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/*
+Several files may be open at once. Each file is kept in a Buffer.
+TaskBox fields Lines, path, modified, cursor and scroll always belong
+to the active buffer. They are stashed to the buffer on switch.
+
+TaskBox created without OpenFiles has no buffers and works with its
+own fields only.
+*/
+type Buffer struct {
+	path     string
+	Lines    []string
+	modified bool
+	cursor   int
+	scroll   int
+	marks    map[rune]int
+}
+
+/*
+Open files and directories. All *.md files are open for directory.
+In workspace mode directory is open as one list of all task lists
+found recursively. See workspace.go
+*/
+func (tb *TaskBox) OpenFiles(paths []string, workspace bool) error {
+	tb.workspace = workspace
+	files, err := tb.listFiles(paths)
+	if err != nil {
+		return err
+	}
+	tb.buffers = nil
+	for _, path := range files {
+		b, err := loadBuffer(path)
+		if err != nil {
+			return err
+		}
+		tb.buffers = append(tb.buffers, b)
+	}
+	tb.current = 0
+	tb.unstash()
+	tb.calculate()
+	if tb.undo != nil {
+		tb.undo = NewUndo(tb) // New Clear Undo
+	}
+	return nil
+}
+
+// Expand directories to *.md files unless in workspace mode
+func (tb *TaskBox) listFiles(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err == nil && info.IsDir() && tb.workspace {
+			files = append(files, path)
+		} else if err == nil && info.IsDir() {
+			md, err := filepath.Glob(filepath.Join(path, "*.md"))
+			if err != nil {
+				return nil, err
+			}
+			if len(md) == 0 {
+				return nil, fmt.Errorf("No *.md files in %s", path)
+			}
+			sort.Strings(md)
+			files = append(files, md...)
+		} else {
+			files = append(files, path)
+		}
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("Nothing to open")
+	}
+	return files, nil
+}
+
+// Load file or workspace directory to new buffer
+func loadBuffer(path string) (*Buffer, error) {
+	t := &TaskBox{}
+	if isDir(path) {
+		if err := t.LoadWorkspace(path); err != nil {
+			return nil, err
+		}
+	} else if err := t.Load(path); err != nil {
+		return nil, err
+	}
+	return &Buffer{path: t.path, Lines: t.Lines}, nil
+}
+
+// Index of buffer open for path, -1 if none
+func (tb *TaskBox) findBuffer(path string) int {
+	for i, b := range tb.buffers {
+		if filepath.Clean(b.path) == filepath.Clean(path) {
+			return i
+		}
+	}
+	return -1
+}
+
+/*
+Open file or directory in place of active buffer. Directory is
+handled as by OpenFiles, its extra files are open after the active
+buffer. File which is already open becomes active. Missing file is
+created on save
+*/
+func (tb *TaskBox) OpenFile(path string) error {
+	files, err := tb.listFiles([]string{path})
+	if err != nil {
+		return err
+	}
+	tb.stash()
+	var loaded []*Buffer
+	for _, path := range files {
+		if tb.findBuffer(path) >= 0 {
+			continue
+		}
+		b, err := loadBuffer(path)
+		if err != nil {
+			return err
+		}
+		loaded = append(loaded, b)
+	}
+	if len(loaded) == 0 {
+		return tb.SwitchBuffer(tb.findBuffer(files[0]))
+	}
+	// Unsaved changes are gone
+	tb.dropEvents(tb.path)
+	if len(tb.buffers) == 0 {
+		tb.buffers = loaded
+		tb.current = 0
+	} else {
+		rest := append(loaded[1:], tb.buffers[tb.current+1:]...)
+		tb.buffers = append(tb.buffers[:tb.current], loaded[0])
+		tb.buffers = append(tb.buffers, rest...)
+	}
+	tb.unstash()
+	tb.calculate()
+	if tb.undo != nil {
+		tb.undo = NewUndo(tb)
+	}
+	return nil
+}
+
+// Save active buffer state
+func (tb *TaskBox) stash() {
+	if len(tb.buffers) == 0 {
+		return
+	}
+	b := tb.buffers[tb.current]
+	b.path = tb.path
+	b.Lines = tb.Lines
+	b.modified = tb.modified
+	b.cursor = tb.cursor
+	b.scroll = tb.scroll
+	b.marks = tb.marks
+}
+
+// Make buffer active
+func (tb *TaskBox) unstash() {
+	b := tb.buffers[tb.current]
+	tb.path = b.path
+	tb.Lines = b.Lines
+	tb.modified = b.modified
+	tb.cursor = b.cursor
+	tb.scroll = b.scroll
+	tb.marks = b.marks
+	tb.ClearSelection()
+}
+
+func (tb *TaskBox) SwitchBuffer(i int) error {
+	if i < 0 || i >= len(tb.buffers) {
+		return fmt.Errorf("No file %d", i+1)
+	}
+	tb.stash()
+	tb.current = i
+	tb.unstash()
+	tb.calculate()
+	return nil
+}
+
+func (tb *TaskBox) NextBuffer() {
+	if len(tb.buffers) > 1 {
+		tb.SwitchBuffer((tb.current + 1) % len(tb.buffers))
+	}
+}
+
+func (tb *TaskBox) PrevBuffer() {
+	if len(tb.buffers) > 1 {
+		tb.SwitchBuffer((tb.current + len(tb.buffers) - 1) % len(tb.buffers))
+	}
+}
+
+// Lines of all buffers. Active buffer lines are taken from TaskBox
+func (tb *TaskBox) allLines() [][]string {
+	if len(tb.buffers) == 0 {
+		return [][]string{tb.Lines}
+	}
+	all := make([][]string, len(tb.buffers))
+	for i, b := range tb.buffers {
+		if i == tb.current {
+			all[i] = tb.Lines
+		} else {
+			all[i] = b.Lines
+		}
+	}
+	return all
+}
+
+func (tb *TaskBox) setModified(i int, modified bool) {
+	if len(tb.buffers) == 0 || i == tb.current {
+		tb.modified = modified
+	} else {
+		tb.buffers[i].modified = modified
+	}
+}
+
+func (tb *TaskBox) isModified(i int) bool {
+	if len(tb.buffers) == 0 || i == tb.current {
+		return tb.modified
+	}
+	return tb.buffers[i].modified
+}
+
+func (tb *TaskBox) AnyModified() bool {
+	for i := range tb.allLines() {
+		if tb.isModified(i) {
+			return true
+		}
+	}
+	return false
+}
+
+// Save all modified buffers
+func (tb *TaskBox) SaveAll() error {
+	if len(tb.buffers) == 0 {
+		return tb.Save(tb.path)
+	}
+	tb.stash()
+	for i, b := range tb.buffers {
+		if i == tb.current {
+			if err := tb.Save(tb.path); err != nil {
+				return err
+			}
+			continue
+		}
+		if b.modified {
+			t := &TaskBox{Lines: b.Lines}
+			if err := t.Save(b.path); err != nil {
+				return err
+			}
+			b.modified = false
+		}
+		if err := tb.saveEvents(b.path, b.path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Move selected lines to the end of another buffer
+func (tb *TaskBox) MoveToBuffer(i int) error {
+	if i < 0 || i >= len(tb.buffers) {
+		return fmt.Errorf("No file %d", i+1)
+	}
+	if i == tb.current {
+		return fmt.Errorf("Lines are already in %s", tb.path)
+	}
+	sel := tb.withNotes(tb.Selection())
+	b := tb.buffers[i]
+	for _, index := range sel {
+		b.Lines = append(b.Lines, tb.Lines[index])
+		tb.logFileEvent(b.path, EventCreated, tb.Lines[index])
+	}
+	for k := len(sel) - 1; k >= 0; k-- {
+		tb.removeLine(sel[k])
+	}
+	tb.ClearSelection()
+	tb.calculate()
+	return nil
+}
+
+// Path of buffer. Active buffer is not stashed yet
+func (tb *TaskBox) bufferPath(i int) string {
+	if i == tb.current {
+		return tb.path
+	}
+	return tb.buffers[i].path
+}
+
+func (tb *TaskBox) bufferName(i int, path string) string {
+	var mark string
+	if tb.isModified(i) {
+		mark = "*"
+	}
+	return fmt.Sprintf("%d:%s%s", i+1, path, mark)
+}
+
+func (tb *TaskBox) BufferNames() []string {
+	names := make([]string, len(tb.buffers))
+	for i := range tb.buffers {
+		names[i] = tb.bufferName(i, tb.bufferPath(i))
+	}
+	return names
+}
+
+func (tb *TaskBox) BufferLine() string {
+	if len(tb.buffers) < 2 {
+		return ""
+	}
+	names := make([]string, len(tb.buffers))
+	for i := range tb.buffers {
+		names[i] = tb.bufferName(i, filepath.Base(tb.bufferPath(i)))
+	}
+	names[tb.current] = "[" + names[tb.current] + "]"
+	return strings.Join(names, " ")
+}
+
+// Make command with buffer number argument. Without argument
+// buffer is chosen from list
+func withBuffer(title string,
+	fn func(tb *TaskBox, i int) error) func(tb *TaskBox, args []string) error {
+	return func(tb *TaskBox, args []string) error {
+		var i int
+		switch len(args) {
+		case 0:
+			i = chooseItem(title, tb.BufferNames(), tb.current)
+			if i < 0 {
+				tb.recordSteps()
+				return nil
+			}
+			tb.recordSteps(fmt.Sprintf("%s %d", tb.lastCommand, i+1))
+		case 1:
+			n, err := strconv.Atoi(args[0])
+			if err != nil {
+				return fmt.Errorf("Not a file number: %s", args[0])
+			}
+			i = n - 1
+		default:
+			return fmt.Errorf("Too many arguments")
+		}
+		return fn(tb, i)
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"github.com/MakeNowJust/heredoc"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func BuffersFixture(t *testing.T) (*TaskBox, string) {
+	dir, err := ioutil.TempDir("", "taskbox")
+	assert.Nil(t, err)
+	ioutil.WriteFile(filepath.Join(dir, "a.md"),
+		[]byte("- [ ] Foo\n- [ ] Bar\n"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "b.md"),
+		[]byte("- [ ] Baz\n"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "c.txt"),
+		[]byte("Qux\n"), 0644)
+	tb := TaskBoxWithUndo()
+	assert.Nil(t, tb.OpenFiles([]string{dir}, false))
+	tb.h = 10
+	return tb, dir
+}
+
+func TestOpenFiles(t *testing.T) {
+	tb, dir := BuffersFixture(t)
+	defer os.RemoveAll(dir)
+
+	assert.Equal(t, 2, len(tb.buffers))
+	assert.Equal(t, filepath.Join(dir, "a.md"), tb.path)
+	assert.Equal(t, "[1:a.md] 2:b.md", tb.BufferLine())
+	assert.False(t, tb.AnyModified())
+
+	tb.CursorDown()
+	assert.Nil(t, tb.Exec("buffer", "2"))
+	assert.Equal(t, filepath.Join(dir, "b.md"), tb.path)
+	assert.Equal(t, tb.String(), "> - [ ] Baz\n")
+	tb.Exec("buffer-next")
+	assert.Equal(t, 1, tb.cursor)
+	assert.Equal(t, "- [ ] Bar", tb.Lines[1])
+
+	assert.EqualError(t, tb.Exec("buffer", "3"), "No file 3")
+	assert.EqualError(t, tb.Exec("buffer", "x"), "Not a file number: x")
+
+	err := tb.OpenFiles([]string{filepath.Join(dir, "c.txt"), dir}, false)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(tb.buffers))
+	assert.Equal(t, []string{"Qux"}, tb.Lines)
+
+	os.Remove(filepath.Join(dir, "a.md"))
+	os.Remove(filepath.Join(dir, "b.md"))
+	err = tb.OpenFiles([]string{dir}, false)
+	assert.EqualError(t, err, "No *.md files in "+dir)
+}
+
+func TestBufferNamesReadOnly(t *testing.T) {
+	tb, dir := BuffersFixture(t)
+	defer os.RemoveAll(dir)
+
+	tb.Lines = []string{"- [ ] New"}
+	tb.modified = true
+	assert.Equal(t, []string{"1:" + filepath.Join(dir, "a.md") + "*",
+		"2:" + filepath.Join(dir, "b.md")}, tb.BufferNames())
+	assert.Equal(t, "[1:a.md*] 2:b.md", tb.BufferLine())
+	assert.Equal(t, []string{"- [ ] Foo", "- [ ] Bar"}, tb.buffers[0].Lines)
+	assert.False(t, tb.buffers[0].modified)
+}
+
+func TestMoveToBuffer(t *testing.T) {
+	tb, dir := BuffersFixture(t)
+	defer os.RemoveAll(dir)
+
+	tb.CursorDown()
+	assert.Nil(t, tb.Exec("move-to", "2"))
+	tb.undo.PutState()
+	assert.Equal(t, tb.InnerString(), "- [ ] Foo\n")
+	assert.Equal(t, []string{"- [ ] Baz", "- [ ] Bar"}, tb.buffers[1].Lines)
+	assert.Equal(t, "[1:a.md*] 2:b.md*", tb.BufferLine())
+
+	assert.EqualError(t, tb.Exec("move-to", "1"),
+		"Lines are already in "+tb.path)
+
+	// Single undo step
+	tb.Exec("buffer", "2")
+	tb.undo.PutState()
+	tb.Exec("undo")
+	assert.Equal(t, 0, tb.current)
+	assert.Equal(t, tb.InnerString(), heredoc.Doc(`
+		- [ ] Foo
+		- [ ] Bar
+	`))
+	assert.Equal(t, []string{"- [ ] Baz"}, tb.buffers[1].Lines)
+
+	tb.Exec("redo")
+	tb.SaveAll()
+	assert.False(t, tb.AnyModified())
+	b, _ := ioutil.ReadFile(filepath.Join(dir, "b.md"))
+	assert.Equal(t, "- [ ] Baz\n- [ ] Bar\n", string(b))
+	b, _ = ioutil.ReadFile(filepath.Join(dir, "a.md"))
+	assert.Equal(t, "- [ ] Foo\n", string(b))
+}
+
+func TestOpenFile(t *testing.T) {
+	tb, dir := BuffersFixture(t)
+	defer os.RemoveAll(dir)
+
+	// Already open
+	assert.Nil(t, tb.OpenFile(filepath.Join(dir, "b.md")))
+	assert.Equal(t, 1, tb.current)
+
+	tb.InsertLine(0, "- [ ] New")
+	tb.modified = true
+	assert.Nil(t, tb.OpenFile(filepath.Join(dir, "c.txt")))
+	assert.Equal(t, "1:a.md [2:c.txt]", tb.BufferLine())
+	assert.Equal(t, []string{"Qux"}, tb.Lines)
+	assert.False(t, tb.modified)
+	assert.Equal(t, 0, tb.undo.stateIndex)
+
+	// Directory is open as *.md files
+	assert.Nil(t, tb.OpenFile(dir))
+	assert.Equal(t, "1:a.md [2:b.md]", tb.BufferLine())
+	assert.EqualError(t, tb.OpenFile(filepath.Join(dir, "a.md", "x")),
+		"open "+filepath.Join(dir, "a.md", "x")+": not a directory")
+	assert.Equal(t, 1, tb.current)
+
+	// Without buffers
+	tb = &TaskBox{Lines: []string{"- [ ] Foo"}}
+	assert.Nil(t, tb.OpenFile(filepath.Join(dir, "a.md")))
+	assert.Equal(t, []string{"- [ ] Foo", "- [ ] Bar"}, tb.Lines)
+	assert.Equal(t, 1, len(tb.buffers))
+}
+
+func TestSaveAs(t *testing.T) {
+	tb, dir := BuffersFixture(t)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "d.md")
+	assert.Nil(t, tb.Exec("save-as", path))
+	assert.Equal(t, path, tb.path)
+	assert.Equal(t, "Saved "+path, tb.message)
+	data, _ := ioutil.ReadFile(path)
+	assert.Equal(t, "- [ ] Foo\n- [ ] Bar\n", string(data))
+	assert.EqualError(t, tb.Exec("save-as", "x", "y"), "Too many arguments")
+
+	missing := filepath.Join(dir, "missing", "e.md")
+	assert.Error(t, tb.Exec("save-as", missing))
+	assert.Equal(t, path, tb.path)
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"github.com/nsf/termbox-go"
+	"github.com/smetana/editbox-go"
+	"strconv"
+	"time"
+)
+
+/*
+Calendar shows month grid with tasks due on each day and the list
+of tasks due on the selected day below it. Week starts on Monday.
+*/
+
+const (
+	calendarWeeks  = 6
+	calendarHeight = calendarWeeks + 3 // with month and weekdays
+	calendarCell   = 3                 // min width of day with space
+)
+
+func (tb *TaskBox) EnterCalendarMode() {
+	if tb.day.IsZero() {
+		tb.day = today()
+	}
+	tb.mode = modeCalendar
+	tb.ClearSelection()
+	tb.cursor = 0
+	tb.scroll = 0
+	tb.calculate()
+}
+
+func (tb *TaskBox) HandleCalendarEvent(ev termbox.Event) {
+	tb.HandleKey(ev)
+}
+
+func today() time.Time {
+	year, month, day := now().Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.Local)
+}
+
+// Tasks due on selected day
+func (tb *TaskBox) calendarView() {
+	tb.view = make([]int, 0)
+	for i, s := range tb.Lines {
+		if lineTypeOf(s) == lineTask && tb.inFilter(s) &&
+			ParseTask(s).Due.Equal(tb.day) {
+			tb.view = append(tb.view, i)
+		}
+	}
+}
+
+// Select another day
+func (tb *TaskBox) MoveDay(days, months int) {
+	tb.day = tb.day.AddDate(0, months, days)
+	tb.cursor = 0
+	tb.scroll = 0
+	tb.calculate()
+}
+
+func (tb *TaskBox) GotoToday() {
+	tb.day = today()
+	tb.cursor = 0
+	tb.calculate()
+}
+
+// Move due date of task under cursor. Calendar follows the task
+func (tb *TaskBox) Reschedule(days int) {
+	i, s := tb.SelectedLine()
+	if i < 0 {
+		return
+	}
+	task := ParseTask(s)
+	due := task.Due
+	if due.IsZero() {
+		due = tb.day
+	}
+	task.SetDue(due.AddDate(0, 0, days))
+	tb.UpdateLine(i, task.String())
+	tb.day = task.Due
+	tb.calculate()
+	tb.CursorToLine(i)
+}
+
+// Number of tasks due on each day keyed by DateFormat date
+func (tb *TaskBox) dueCounts() map[string]int {
+	counts := make(map[string]int)
+	for _, s := range tb.Lines {
+		if lineTypeOf(s) == lineTask && tb.inFilter(s) {
+			if due := ParseTask(s).Due; !due.IsZero() {
+				counts[due.Format(DateFormat)]++
+			}
+		}
+	}
+	return counts
+}
+
+// First day of calendar grid
+func gridStart(day time.Time) time.Time {
+	first := day.AddDate(0, 0, 1-day.Day())
+	return first.AddDate(0, 0, -(int(first.Weekday())+6)%7)
+}
+
+// Draw month grid at row y above task list
+func (tb *TaskBox) renderCalendar(y int) {
+	cw := tb.w / 7
+	editbox.Label(tb.x, y, tb.w, theme.Heading.Fg, theme.Heading.Bg,
+		tb.day.Format("January 2006"))
+	for d, name := range []string{"Mo", "Tu", "We", "Th", "Fr", "Sa", "Su"} {
+		editbox.Label(tb.x+d*cw, y+1, cw, theme.Heading.Fg,
+			theme.Heading.Bg, name)
+	}
+	counts := tb.dueCounts()
+	day := gridStart(tb.day)
+	for week := 0; week < calendarWeeks; week++ {
+		for d := 0; d < 7; d++ {
+			style := theme.Normal
+			switch {
+			case day.Equal(tb.day):
+				style = theme.Cursor
+			case day.Equal(today()):
+				style = theme.Heading
+			case day.Month() != tb.day.Month():
+				style = theme.Archived
+			}
+			s := strconv.Itoa(day.Day())
+			if n := counts[day.Format(DateFormat)]; n > 0 {
+				s = fmt.Sprintf("%s (%d)", s, n)
+			}
+			editbox.Label(tb.x+d*cw, y+2+week, cw-1, style.Fg, style.Bg, s)
+			day = day.AddDate(0, 0, 1)
+		}
+	}
+}
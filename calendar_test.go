@@ -0,0 +1,68 @@
+package main
+
+import (
+	"github.com/MakeNowJust/heredoc"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestSetDue(t *testing.T) {
+	task := ParseTask("- [ ] foo")
+	day := time.Date(2026, 10, 21, 0, 0, 0, 0, time.Local)
+	task.SetDue(day)
+	assert.Equal(t, "foo due:2026-10-21", task.Description)
+	task.SetDue(day.AddDate(0, 0, 1))
+	assert.Equal(t, "foo due:2026-10-22", task.Description)
+	task.SetDue(time.Time{})
+	assert.Equal(t, "foo", task.Description)
+}
+
+func TestGridStart(t *testing.T) {
+	day := time.Date(2026, 10, 21, 0, 0, 0, 0, time.Local)
+	// October 1st 2026 is Thursday
+	assert.Equal(t, "2026-09-28", gridStart(day).Format(DateFormat))
+	day = time.Date(2026, 6, 10, 0, 0, 0, 0, time.Local)
+	// June 1st 2026 is Monday
+	assert.Equal(t, "2026-06-01", gridStart(day).Format(DateFormat))
+}
+
+func TestCalendar(t *testing.T) {
+	now = func() time.Time { return time.Date(2026, 10, 21, 15, 0, 0, 0, time.Local) }
+	defer func() { now = time.Now }()
+	tb := &TaskBox{Lines: []string{
+		"- [ ] foo due:2026-10-21",
+		"- [ ] bar due:2026-10-22",
+		"- [ ] baz due:2026-10-21",
+		"- [ ] qux",
+	}}
+	tb.h = 5
+	tb.EnterCalendarMode()
+	assert.Equal(t, heredoc.Doc(`
+		> - [ ] foo due:2026-10-21
+		  - [ ] baz due:2026-10-21
+	`), tb.String())
+	assert.Equal(t, map[string]int{
+		today().Format(DateFormat):                  2,
+		today().AddDate(0, 0, 1).Format(DateFormat): 1,
+	}, tb.dueCounts())
+
+	tb.MoveDay(1, 0)
+	assert.Equal(t, heredoc.Doc(`
+		> - [ ] bar due:2026-10-22
+	`), tb.String())
+
+	tb.MoveDay(7, 0)
+	assert.Equal(t, heredoc.Doc(`
+		> No tasks due on 2026-10-29
+	`), tb.String())
+
+	tb.GotoToday()
+	tb.CursorDown()
+	tb.Reschedule(7)
+	assert.Equal(t, "- [ ] baz due:2026-10-28", tb.Lines[2])
+	assert.Equal(t, "2026-10-28", tb.day.Format(DateFormat))
+	assert.Equal(t, heredoc.Doc(`
+		> - [ ] baz due:2026-10-28
+	`), tb.String())
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"unicode/utf8"
+)
+
+/*
+Yanked lines are stored in registers:
+
+	"     default register
+	a-z   named registers
+	+     system clipboard
+
+Register "+" is also kept in memory so it works even when system
+clipboard is not available.
+*/
+const (
+	defaultRegister   = '"'
+	clipboardRegister = '+'
+)
+
+type Clipboard interface {
+	Copy(s string) error
+	Paste() (string, error)
+}
+
+var errNoPaste = errors.New("Clipboard does not support paste")
+
+// Clipboard via external programs e.g. xclip
+type commandClipboard struct {
+	copy, paste []string
+}
+
+func (c commandClipboard) Copy(s string) error {
+	cmd := exec.Command(c.copy[0], c.copy[1:]...)
+	cmd.Stdin = strings.NewReader(s)
+	return cmd.Run()
+}
+
+func (c commandClipboard) Paste() (string, error) {
+	out, err := exec.Command(c.paste[0], c.paste[1:]...).Output()
+	return string(out), err
+}
+
+// Clipboard via OSC 52 terminal escape sequence. Copy only
+type osc52Clipboard struct {
+	w io.Writer
+}
+
+func (c osc52Clipboard) Copy(s string) error {
+	_, err := fmt.Fprintf(c.w, "\x1b]52;c;%s\x07",
+		base64.StdEncoding.EncodeToString([]byte(s)))
+	return err
+}
+
+func (c osc52Clipboard) Paste() (string, error) {
+	return "", errNoPaste
+}
+
+var clipboardCommands = []struct {
+	env         string // required environment variable
+	copy, paste []string
+}{
+	{"WAYLAND_DISPLAY", []string{"wl-copy"}, []string{"wl-paste", "--no-newline"}},
+	{"DISPLAY", []string{"xclip", "-selection", "clipboard"},
+		[]string{"xclip", "-selection", "clipboard", "-o"}},
+	{"DISPLAY", []string{"xsel", "--clipboard", "--input"},
+		[]string{"xsel", "--clipboard", "--output"}},
+	{"", []string{"pbcopy"}, []string{"pbpaste"}},
+}
+
+var clipboard Clipboard
+
+/*
+Find system clipboard for mode:
+
+	auto    external program if found, OSC 52 otherwise
+	osc52   OSC 52 only
+	off     no system clipboard
+*/
+func DetectClipboard(mode string) (Clipboard, error) {
+	switch mode {
+	case "", "auto":
+		for _, c := range clipboardCommands {
+			if c.env != "" && os.Getenv(c.env) == "" {
+				continue
+			}
+			if _, err := exec.LookPath(c.copy[0]); err != nil {
+				continue
+			}
+			if _, err := exec.LookPath(c.paste[0]); err != nil {
+				continue
+			}
+			return commandClipboard{copy: c.copy, paste: c.paste}, nil
+		}
+		return osc52Clipboard{os.Stdout}, nil
+	case "osc52":
+		return osc52Clipboard{os.Stdout}, nil
+	case "off":
+		return nil, nil
+	}
+	return nil, fmt.Errorf("Unknown clipboard: %s (auto,osc52,off)", mode)
+}
+
+func parseRegister(args []string) (rune, error) {
+	switch len(args) {
+	case 0:
+		return defaultRegister, nil
+	case 1:
+		r, size := utf8.DecodeRuneInString(args[0])
+		if size == len(args[0]) &&
+			(r == defaultRegister || r == clipboardRegister ||
+				(r >= 'a' && r <= 'z')) {
+			return r, nil
+		}
+		return 0, fmt.Errorf("Unknown register: %s", args[0])
+	}
+	return 0, fmt.Errorf("Too many arguments")
+}
+
+func (tb *TaskBox) setRegister(r rune, lines []string) error {
+	if tb.registers == nil {
+		tb.registers = make(map[rune][]string)
+	}
+	tb.registers[r] = lines
+	if r != defaultRegister {
+		tb.registers[defaultRegister] = lines
+	}
+	if r == clipboardRegister && clipboard != nil {
+		return clipboard.Copy(strings.Join(lines, "\n") + "\n")
+	}
+	return nil
+}
+
+func (tb *TaskBox) getRegister(r rune) []string {
+	if r == clipboardRegister && clipboard != nil {
+		s, err := clipboard.Paste()
+		if err == nil && s != "" {
+			s = strings.ReplaceAll(s, "\r\n", "\n")
+			return strings.Split(strings.TrimRight(s, "\n"), "\n")
+		}
+	}
+	return tb.registers[r]
+}
+
+// Copy selected lines to register
+func (tb *TaskBox) Yank(r rune) error {
+	sel := tb.withNotes(tb.Selection())
+	if len(sel) == 0 {
+		return nil
+	}
+	lines := make([]string, len(sel))
+	for k, i := range sel {
+		lines[k] = tb.Lines[i]
+	}
+	tb.ClearSelection()
+	return tb.setRegister(r, lines)
+}
+
+// Yank and delete selected lines
+func (tb *TaskBox) Cut(r rune) error {
+	sel := tb.withNotes(tb.Selection())
+	err := tb.Yank(r)
+	for k := len(sel) - 1; k >= 0; k-- {
+		tb.removeLine(sel[k])
+	}
+	tb.calculate()
+	return err
+}
+
+// Insert lines from register after (or before) the line under cursor
+func (tb *TaskBox) Paste(r rune, before bool) error {
+	lines := tb.getRegister(r)
+	if len(lines) == 0 {
+		return fmt.Errorf("Register %c is empty", r)
+	}
+	i, _ := tb.SelectedLine()
+	if i < 0 {
+		i = 0
+	} else if !before {
+		i = tb.blockEnd(i) + 1
+	}
+	ids := tb.taskIDs()
+	for k, line := range lines {
+		tb.InsertLine(i+k, line)
+		if ids[lineID(line)] {
+			// Pasted copy
+			tb.renewID(i + k)
+		}
+		tb.logEvent(EventCreated, tb.Lines[i+k])
+	}
+	tb.calculate()
+	for pos, index := range tb.view {
+		if index >= i {
+			tb.cursor = pos
+			break
+		}
+	}
+	tb.scrollToCursor()
+	return nil
+}
+
+// Make command with optional register argument
+func withRegister(fn func(tb *TaskBox, r rune) error) func(tb *TaskBox, args []string) error {
+	return func(tb *TaskBox, args []string) error {
+		r, err := parseRegister(args)
+		if err != nil {
+			return err
+		}
+		return fn(tb, r)
+	}
+}
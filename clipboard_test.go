@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"github.com/MakeNowJust/heredoc"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type fakeClipboard struct {
+	text string
+}
+
+func (c *fakeClipboard) Copy(s string) error {
+	c.text = s
+	return nil
+}
+
+func (c *fakeClipboard) Paste() (string, error) {
+	return c.text, nil
+}
+
+func TestYankPaste(t *testing.T) {
+	tb := TaskBoxFixture(4)
+	tb.ToggleVisual()
+	tb.CursorDown()
+	assert.Nil(t, tb.Exec("yank"))
+	assert.False(t, tb.HasSelection())
+	tb.cursor = 3
+	assert.Nil(t, tb.Exec("paste"))
+	assert.Equal(t, tb.InnerString(), heredoc.Doc(`
+		foo
+		bar
+		baz
+		qux
+		foo
+		bar
+	`))
+	assert.Equal(t, 4, tb.cursor)
+
+	tb.cursor = 0
+	assert.Nil(t, tb.Exec("paste-before"))
+	assert.Equal(t, tb.InnerString(), heredoc.Doc(`
+		foo
+		bar
+		foo
+		bar
+		baz
+		qux
+		foo
+		bar
+	`))
+	assert.Equal(t, 0, tb.cursor)
+}
+
+func TestCutToRegister(t *testing.T) {
+	tb := TaskBoxFixture(4)
+	tb.cursor = 2
+	assert.Nil(t, tb.Exec("cut", "a"))
+	tb.cursor = 0
+	assert.Nil(t, tb.Exec("yank"))
+	assert.Nil(t, tb.Exec("paste", "a"))
+	assert.Equal(t, tb.InnerString(), heredoc.Doc(`
+		foo
+		baz
+		bar
+		qux
+	`))
+
+	assert.EqualError(t, tb.Exec("paste", "b"), "Register b is empty")
+	assert.EqualError(t, tb.Exec("paste", "ab"), "Unknown register: ab")
+	assert.EqualError(t, tb.Exec("paste", "A"), "Unknown register: A")
+}
+
+func TestSystemClipboard(t *testing.T) {
+	saved := clipboard
+	defer func() { clipboard = saved }()
+	fake := &fakeClipboard{}
+	clipboard = fake
+
+	tb := TaskBoxFixture(3)
+	tb.ToggleMark()
+	tb.CursorDown()
+	tb.ToggleMark()
+	assert.Nil(t, tb.Exec("yank", "+"))
+	assert.Equal(t, "foo\nbaz\n", fake.text)
+
+	fake.text = "- [ ] From\r\n- [ ] Chat\r\n"
+	assert.Nil(t, tb.Exec("paste", "+"))
+	assert.Equal(t, tb.InnerString(), heredoc.Doc(`
+		foo
+		bar
+		baz
+		- [ ] From
+		- [ ] Chat
+	`))
+}
+
+func TestOSC52Clipboard(t *testing.T) {
+	var b bytes.Buffer
+	c := osc52Clipboard{&b}
+	assert.Nil(t, c.Copy("foo\n"))
+	assert.Equal(t, "\x1b]52;c;Zm9vCg==\x07", b.String())
+	_, err := c.Paste()
+	assert.Equal(t, errNoPaste, err)
+
+	// Falls back to the copy in memory
+	saved := clipboard
+	defer func() { clipboard = saved }()
+	clipboard = c
+	tb := TaskBoxFixture(2)
+	tb.Exec("yank", "+")
+	tb.Exec("paste", "+")
+	assert.Equal(t, tb.InnerString(), "foo\nfoo\nbar\n")
+}
+
+func TestDetectClipboard(t *testing.T) {
+	c, err := DetectClipboard("off")
+	assert.Nil(t, err)
+	assert.Nil(t, c)
+	c, err = DetectClipboard("osc52")
+	assert.Nil(t, err)
+	assert.IsType(t, osc52Clipboard{}, c)
+	_, err = DetectClipboard("foo")
+	assert.EqualError(t, err, "Unknown clipboard: foo (auto,osc52,off)")
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+/*
+Command line runs any command by name, see commands.go. Tab completes
+command names and arguments, Up and Down go through command history
+of the session
+*/
+
+// Short names of commands
+var commandAliases = map[string]string{
+	"w": "save",
+	"q": "quit",
+}
+
+var commandHistory []string
+
+// Values of command arguments for completion. Last of args is being
+// completed, values are filtered by it later
+var argCompleters = map[string]func(tb *TaskBox, args []string) []string{
+	"filter": func(tb *TaskBox, args []string) []string {
+		values := []string{"All", "Open", "Closed", "Actionable", "tag:"}
+		for _, tag := range tb.tags() {
+			values = append(values, "tag:"+tag)
+		}
+		return values
+	},
+	"sort": func(tb *TaskBox, args []string) []string {
+		return sortKeyNames()
+	},
+	"archive": func(tb *TaskBox, args []string) []string {
+		return []string{"closed"}
+	},
+	"time-report": func(tb *TaskBox, args []string) []string {
+		if len(args) == 2 {
+			return completePath(args[1])
+		}
+		return []string{"task", "tag", "day"}
+	},
+	"goto": func(tb *TaskBox, args []string) []string {
+		var ids []string
+		for id := range tb.taskIDs() {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		return ids
+	},
+	"save":    completePathArg,
+	"save-as": completePathArg,
+	"open":    completePathArg,
+}
+
+func completePathArg(tb *TaskBox, args []string) []string {
+	return completePath(args[len(args)-1])
+}
+
+// Tags of tasks in current file
+func (tb *TaskBox) tags() []string {
+	seen := make(map[string]bool)
+	var tags []string
+	for _, s := range tb.Lines {
+		if lineTypeOf(s) != lineTask {
+			continue
+		}
+		for _, tag := range ParseTask(s).Tags {
+			if !seen[tag] {
+				seen[tag] = true
+				tags = append(tags, tag)
+			}
+		}
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// Files and directories starting with path. Directories end with /
+func completePath(path string) []string {
+	dir, _ := filepath.Split(path)
+	read := dir
+	if read == "" {
+		read = "."
+	}
+	files, err := ioutil.ReadDir(read)
+	if err != nil {
+		return nil
+	}
+	var paths []string
+	for _, f := range files {
+		name := dir + f.Name()
+		if f.IsDir() {
+			name += string(os.PathSeparator)
+		}
+		paths = append(paths, name)
+	}
+	return paths
+}
+
+// Command lines which complete line
+func (tb *TaskBox) Complete(line string) []string {
+	fields := strings.Fields(line)
+	if len(fields) == 0 || strings.HasSuffix(line, " ") {
+		fields = append(fields, "")
+	}
+	last := fields[len(fields)-1]
+	var values []string
+	if len(fields) == 1 {
+		for _, name := range commandNames {
+			if commands[name].AvailableIn(tb.mode) {
+				values = append(values, name)
+			}
+		}
+	} else if complete, ok := argCompleters[commandName(fields[0])]; ok {
+		values = complete(tb, fields[1:])
+	}
+	prefix := strings.Join(fields[:len(fields)-1], " ")
+	if prefix != "" {
+		prefix += " "
+	}
+	var lines []string
+	for _, v := range values {
+		if strings.HasPrefix(v, last) {
+			lines = append(lines, prefix+v)
+		}
+	}
+	return lines
+}
+
+func commandName(name string) string {
+	if alias, ok := commandAliases[name]; ok {
+		return alias
+	}
+	return name
+}
+
+func commonPrefix(lines []string) string {
+	if len(lines) == 0 {
+		return ""
+	}
+	prefix := lines[0]
+	for _, s := range lines[1:] {
+		for !strings.HasPrefix(s, prefix) {
+			_, size := utf8.DecodeLastRuneInString(prefix)
+			prefix = prefix[:len(prefix)-size]
+		}
+	}
+	return prefix
+}
+
+func addHistory(line string) {
+	n := len(commandHistory)
+	if line != "" && (n == 0 || commandHistory[n-1] != line) {
+		commandHistory = append(commandHistory, line)
+	}
+}
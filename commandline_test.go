@@ -0,0 +1,102 @@
+package main
+
+import (
+	"github.com/MakeNowJust/heredoc"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestComplete(t *testing.T) {
+	tb := &TaskBox{Lines: []string{"- [ ] foo #ops", "- [ ] bar #dev #ops"}}
+	tb.calculate()
+
+	assert.Equal(t, []string{"filter"}, tb.Complete("fil"))
+	assert.Equal(t, []string{"page-up", "page-down", "paste", "paste-before"},
+		tb.Complete("pa"))
+	assert.Equal(t, []string{"filter tag:", "filter tag:dev", "filter tag:ops"},
+		tb.Complete("filter t"))
+	assert.Equal(t, []string{"sort due"}, tb.Complete(" sort  d"))
+	assert.Equal(t, []string{"archive closed"}, tb.Complete("archive "))
+	assert.Empty(t, tb.Complete("toggle "))
+	assert.Empty(t, tb.Complete("foo"))
+
+	tb.mode = modeCalendar
+	assert.Equal(t, []string{"reschedule"}, tb.Complete("res"))
+
+	assert.Equal(t, "paste", commonPrefix([]string{"paste", "paste-before"}))
+	assert.Equal(t, "ё", commonPrefix([]string{"ёж", "ёлка"}))
+	assert.Equal(t, "", commonPrefix(nil))
+}
+
+func TestCompletePath(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "complete")
+	defer os.RemoveAll(dir)
+	os.Mkdir(filepath.Join(dir, "sub"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "TODO.md"), nil, 0644)
+
+	sep := string(os.PathSeparator)
+	assert.Equal(t, []string{dir + sep + "TODO.md", dir + sep + "sub" + sep},
+		completePath(dir+sep))
+	tb := &TaskBox{}
+	assert.Equal(t, []string{"w " + dir + sep + "sub" + sep},
+		tb.Complete("w "+dir+sep+"s"))
+}
+
+func TestCommandHistory(t *testing.T) {
+	defer func() { commandHistory = nil }()
+	addHistory("sort due")
+	addHistory("sort due")
+	addHistory("")
+	addHistory("filter Open")
+	assert.Equal(t, []string{"sort due", "filter Open"}, commandHistory)
+}
+
+func TestFilterTagAndArchiveClosed(t *testing.T) {
+	tb := &TaskBox{Lines: []string{
+		"- [ ] foo #ops",
+		"- [x] bar #ops",
+		"  note",
+		"- [x] baz",
+	}}
+	tb.h = 5
+	tb.calculate()
+
+	assert.Nil(t, tb.ExecLine("filter tag:ops"))
+	assert.Equal(t, heredoc.Doc(`
+		> - [ ] foo #ops
+		  - [x] bar #ops
+	`), tb.String())
+	assert.Nil(t, tb.ExecLine("filter tag:"))
+	assert.Equal(t, "", tb.tag)
+
+	assert.Nil(t, tb.ExecLine("filter Open"))
+	assert.Nil(t, tb.ExecLine("archive closed"))
+	assert.Equal(t, []string{
+		"- [ ] foo #ops",
+		"<!-- - [x] bar #ops -->",
+		"<!--   note -->",
+		"<!-- - [x] baz -->",
+	}, tb.Lines)
+	assert.Equal(t, "2 tasks archived", tb.message)
+	assert.EqualError(t, tb.ExecLine("archive foo"), "Unexpected arguments: foo")
+}
+
+func TestCommandAliases(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "alias")
+	defer os.RemoveAll(dir)
+	tb := &TaskBox{Lines: []string{"- [ ] foo"}}
+	tb.calculate()
+	path := filepath.Join(dir, "other.md")
+	assert.Nil(t, tb.ExecLine("w "+path))
+	assert.Equal(t, path, tb.path)
+	assert.Equal(t, "save", tb.lastCommand)
+	assert.Equal(t, "Saved "+path, tb.message)
+	data, _ := ioutil.ReadFile(path)
+	assert.Equal(t, "- [ ] foo\n", string(data))
+
+	assert.Nil(t, tb.ExecLine("q"))
+	assert.Equal(t, modeExit, tb.mode)
+}
@@ -0,0 +1,370 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+/*
+Command is a named operation. Key handlers dispatch to commands by
+name so the same operation can be run from the command prompt, from
+key bindings or directly from tests without faking termbox events:
+
+	tb.Exec("toggle")
+	tb.ExecLine("filter Open")
+*/
+type Command struct {
+	Name  string
+	Desc  string
+	Modes []mode
+	Run   func(tb *TaskBox, args []string) error
+}
+
+var (
+	commands     = make(map[string]*Command)
+	commandNames []string // in order of registration
+)
+
+var (
+	inTask     = []mode{modeTask}
+	inEdit     = []mode{modeEdit}
+	inCalendar = []mode{modeCalendar}
+	inBrowse   = []mode{modeTask, modeArchive, modeAgenda, modeCalendar}
+	inAll      = []mode{modeTask, modeEdit, modeArchive, modeAgenda, modeCalendar}
+	// Modes where tasks are changed in place
+	inTasks = []mode{modeTask, modeAgenda, modeCalendar}
+	// Modes other than task list
+	inViews = []mode{modeArchive, modeAgenda, modeCalendar}
+)
+
+func addCommand(name, desc string, modes []mode,
+	run func(tb *TaskBox, args []string) error) {
+	if _, ok := commands[name]; ok {
+		panic("Duplicate command: " + name)
+	}
+	commands[name] = &Command{Name: name, Desc: desc, Modes: modes, Run: run}
+	commandNames = append(commandNames, name)
+}
+
+// Wrap operation which takes no arguments
+func noArgs(fn func(tb *TaskBox)) func(tb *TaskBox, args []string) error {
+	return noArgsErr(func(tb *TaskBox) error {
+		fn(tb)
+		return nil
+	})
+}
+
+// Wrap operation which takes no arguments and may fail
+func noArgsErr(fn func(tb *TaskBox) error) func(tb *TaskBox, args []string) error {
+	return func(tb *TaskBox, args []string) error {
+		if len(args) > 0 {
+			return fmt.Errorf("Unexpected arguments: %s",
+				strings.Join(args, " "))
+		}
+		return fn(tb)
+	}
+}
+
+func (c *Command) AvailableIn(m mode) bool {
+	for _, cm := range c.Modes {
+		if cm == m {
+			return true
+		}
+	}
+	return false
+}
+
+func (tb *TaskBox) Exec(name string, args ...string) error {
+	c, ok := commands[commandName(name)]
+	if !ok {
+		return fmt.Errorf("Unknown command: %s", name)
+	}
+	if !c.AvailableIn(tb.mode) {
+		return fmt.Errorf("Command %s is not available in %s mode",
+			c.Name, tb.mode)
+	}
+	tb.lastCommand = c.Name
+	return c.Run(tb, args)
+}
+
+// Execute command line e.g. "filter Open"
+func (tb *TaskBox) ExecLine(line string) error {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return nil
+	}
+	if _, err := strconv.Atoi(fields[0]); err == nil && len(fields) == 1 {
+		// :42 goes to line 42
+		return tb.Exec("line", fields[0])
+	}
+	return tb.Exec(fields[0], fields[1:]...)
+}
+
+func init() {
+	// Cursor
+	addCommand("up", "cursor up", inBrowse, noArgs((*TaskBox).CursorUp))
+	addCommand("down", "cursor down", inBrowse, noArgs((*TaskBox).CursorDown))
+	addCommand("page-up", "page up", inBrowse, noArgs((*TaskBox).PageUp))
+	addCommand("page-down", "page down", inBrowse, noArgs((*TaskBox).PageDown))
+	addCommand("first", "first line", inBrowse,
+		noArgs(func(tb *TaskBox) { tb.GotoLine(1) }))
+	addCommand("last", "last line", inBrowse,
+		noArgs(func(tb *TaskBox) { tb.GotoLine(len(tb.view)) }))
+	addCommand("line", "go to line [N]", inBrowse,
+		func(tb *TaskBox, args []string) error {
+			if len(args) != 1 {
+				return fmt.Errorf("Expected line number")
+			}
+			n, err := strconv.Atoi(args[0])
+			if err != nil {
+				return fmt.Errorf("Not a number: %s", args[0])
+			}
+			tb.GotoLine(n)
+			return nil
+		})
+	addCommand("set-mark", "set mark [a-z] on line", inTask,
+		func(tb *TaskBox, args []string) error {
+			if len(args) != 1 {
+				return fmt.Errorf("Expected mark")
+			}
+			return tb.SetMark(args[0])
+		})
+	addCommand("jump-mark", "jump to mark [a-z]", inTask,
+		func(tb *TaskBox, args []string) error {
+			if len(args) != 1 {
+				return fmt.Errorf("Expected mark")
+			}
+			return tb.JumpToMark(args[0])
+		})
+
+	// Tasks
+	addCommand("edit", "edit", inTasks, noArgs((*TaskBox).EnterEditMode))
+	addCommand("insert", "insert line", inTask,
+		noArgs((*TaskBox).InsertLineAndEdit))
+	addCommand("delete", "delete line", inTask,
+		noArgs((*TaskBox).TaskDeleteKey))
+	addCommand("toggle", "toggle status", inTasks,
+		noArgs((*TaskBox).ToggleTask))
+	addCommand("move-up", "move line up", inTask,
+		noArgs((*TaskBox).MoveLineUp))
+	addCommand("move-down", "move line down", inTask,
+		noArgs((*TaskBox).MoveLineDown))
+	addCommand("move-bottom", "move line to the bottom", inTask,
+		noArgs((*TaskBox).MoveLineToBottom))
+	addCommand("copy", "insert copy of the line", inBrowse,
+		noArgs((*TaskBox).CopyLine))
+	addCommand("archive", "archive line (unarchive line) [closed]", inBrowse,
+		func(tb *TaskBox, args []string) error {
+			switch {
+			case len(args) == 0:
+				tb.ToggleComment()
+			case len(args) == 1 && args[0] == "closed":
+				tb.ArchiveClosed()
+			default:
+				return fmt.Errorf("Unexpected arguments: %s",
+					strings.Join(args, " "))
+			}
+			return nil
+		})
+	addCommand("visual", "select range of lines", inTask,
+		noArgs((*TaskBox).ToggleVisual))
+	addCommand("mark", "mark line (unmark line)", inTask,
+		noArgs((*TaskBox).ToggleMark))
+	addCommand("clear-selection", "clear selection", inTask,
+		noArgs((*TaskBox).ClearSelection))
+	addCommand("yank", "copy lines to register [a-z\"+]", inTask,
+		withRegister((*TaskBox).Yank))
+	addCommand("cut", "cut lines to register [a-z\"+]", inTask,
+		withRegister((*TaskBox).Cut))
+	addCommand("paste", "paste lines from register [a-z\"+]", inTask,
+		withRegister(func(tb *TaskBox, r rune) error {
+			return tb.Paste(r, false)
+		}))
+	addCommand("paste-before", "paste lines above from register [a-z\"+]",
+		inTask, withRegister(func(tb *TaskBox, r rune) error {
+			return tb.Paste(r, true)
+		}))
+	addCommand("priority-up", "raise priority", inTasks,
+		noArgs(func(tb *TaskBox) { tb.ChangePriority(1) }))
+	addCommand("priority-down", "lower priority", inTasks,
+		noArgs(func(tb *TaskBox) { tb.ChangePriority(-1) }))
+	addCommand("sort-view", "view tasks by priority and due date", inTask,
+		noArgs((*TaskBox).ToggleSortedView))
+	addCommand("sort", "sort tasks in section or selection [key]", inTask,
+		func(tb *TaskBox, args []string) error {
+			switch len(args) {
+			case 0:
+				i := chooseItem("Sort by", sortKeyNames(), 0)
+				if i < 0 {
+					tb.recordSteps()
+					return nil
+				}
+				tb.recordSteps("sort " + sortKeys[i].name)
+				return tb.Sort(sortKeys[i].name)
+			case 1:
+				return tb.Sort(args[0])
+			}
+			return fmt.Errorf("Too many arguments")
+		})
+	addCommand("notes", "show notes in the list (hide notes)", inBrowse,
+		noArgs((*TaskBox).ToggleNotes))
+	addCommand("add-note", "add note to task", inTask,
+		noArgs((*TaskBox).AddNote))
+	addCommand("history", "show task history", inBrowse,
+		noArgsErr((*TaskBox).ShowHistory))
+	addCommand("add-id", "give ids to tasks", inBrowse,
+		noArgs((*TaskBox).AddID))
+	addCommand("goto", "go to task with id", inBrowse,
+		func(tb *TaskBox, args []string) error {
+			if len(args) != 1 {
+				return fmt.Errorf("Expected task id")
+			}
+			return tb.GotoTask(strings.TrimPrefix(args[0], "id:"))
+		})
+	addCommand("stats", "show statistics", inBrowse,
+		noArgsErr((*TaskBox).ShowStats))
+	addCommand("timer", "start timer for task (stop timer)", inTasks,
+		noArgsErr((*TaskBox).ToggleTimer))
+	addCommand("time-report", "time spent [task|tag|day] [file.csv]", inBrowse,
+		(*TaskBox).ShowTimeReport)
+	addCommand("filter", "change filter [All|Open|Closed|Actionable|tag:TAG]",
+		inTasks, func(tb *TaskBox, args []string) error {
+			switch {
+			case len(args) == 0:
+				tb.NextFilter()
+			case strings.HasPrefix(args[0], "tag:") && len(args) == 1:
+				tb.FilterTag(strings.TrimPrefix(args[0], "tag:"))
+			case len(args) == 1:
+				s := StatusFromString(args[0])
+				if s.String() != args[0] {
+					return fmt.Errorf("Unknown filter: %s", args[0])
+				}
+				tb.Filter(s)
+			default:
+				return fmt.Errorf("Too many arguments")
+			}
+			return nil
+		})
+
+	addCommand("record", "record macro to register [a-z] (stop recording)",
+		inAll, (*TaskBox).RecordMacro)
+	addCommand("play", "play macro [a-z] [count]", inAll,
+		func(tb *TaskBox, args []string) error {
+			count := 1
+			switch len(args) {
+			case 1:
+			case 2:
+				n, err := strconv.Atoi(args[1])
+				if err != nil {
+					return fmt.Errorf("Not a number: %s", args[1])
+				}
+				if n > 0 {
+					count = n
+				}
+			default:
+				return fmt.Errorf("Expected macro register")
+			}
+			return tb.PlayMacro(args[0], count)
+		})
+	addCommand("save-macros", "save macros to config file", inBrowse,
+		noArgsErr(func(tb *TaskBox) error {
+			if err := SaveMacros(configFile, tb.macros); err != nil {
+				return err
+			}
+			tb.message = "Macros saved to " + configFile
+			return nil
+		}))
+
+	// Calendar
+	addCommand("next-day", "next day", inCalendar,
+		noArgs(func(tb *TaskBox) { tb.MoveDay(1, 0) }))
+	addCommand("prev-day", "previous day", inCalendar,
+		noArgs(func(tb *TaskBox) { tb.MoveDay(-1, 0) }))
+	addCommand("next-week", "next week", inCalendar,
+		noArgs(func(tb *TaskBox) { tb.MoveDay(7, 0) }))
+	addCommand("prev-week", "previous week", inCalendar,
+		noArgs(func(tb *TaskBox) { tb.MoveDay(-7, 0) }))
+	addCommand("next-month", "next month", inCalendar,
+		noArgs(func(tb *TaskBox) { tb.MoveDay(0, 1) }))
+	addCommand("prev-month", "previous month", inCalendar,
+		noArgs(func(tb *TaskBox) { tb.MoveDay(0, -1) }))
+	addCommand("today", "go to today", inCalendar,
+		noArgs((*TaskBox).GotoToday))
+	addCommand("reschedule", "move task due date by [N] days", inCalendar,
+		func(tb *TaskBox, args []string) error {
+			if len(args) != 1 {
+				return fmt.Errorf("Expected number of days")
+			}
+			days, err := strconv.Atoi(args[0])
+			if err != nil {
+				return fmt.Errorf("Not a number: %s", args[0])
+			}
+			tb.Reschedule(days)
+			return nil
+		})
+
+	// Edit
+	addCommand("stop-edit", "stop edit", inEdit,
+		noArgs((*TaskBox).ExitEditMode))
+	addCommand("split", "split line", inEdit,
+		noArgs((*TaskBox).EditEnterKey))
+	addCommand("task-prefix", "insert \"- [ ]\"", inEdit,
+		noArgs((*TaskBox).AddTaskPrefix))
+	addCommand("edit-up", "edit line above", inEdit,
+		noArgs((*TaskBox).EditMoveUp))
+	addCommand("edit-down", "edit line below", inEdit,
+		noArgs((*TaskBox).EditMoveDown))
+	addCommand("edit-page-up", "edit line page up", inEdit,
+		noArgs((*TaskBox).EditMovePageUp))
+	addCommand("edit-page-down", "edit line page down", inEdit,
+		noArgs((*TaskBox).EditMovePageDown))
+
+	// Modes
+	addCommand("archive-mode", "go to archive", inTask,
+		noArgs((*TaskBox).EnterArchiveMode))
+	addCommand("agenda-mode", "view tasks by due date", inTask,
+		noArgs((*TaskBox).EnterAgendaMode))
+	addCommand("calendar-mode", "view month calendar", inTask,
+		noArgs((*TaskBox).EnterCalendarMode))
+	addCommand("task-mode", "return to tasks", inViews,
+		noArgs((*TaskBox).EnterTaskMode))
+
+	// Files
+	addCommand("buffer", "switch to file [N]", inBrowse,
+		withBuffer("Switch to file", (*TaskBox).SwitchBuffer))
+	addCommand("buffer-next", "next file", inBrowse,
+		noArgs((*TaskBox).NextBuffer))
+	addCommand("buffer-prev", "previous file", inBrowse,
+		noArgs((*TaskBox).PrevBuffer))
+	addCommand("move-to", "move lines to the end of file [N]", inTask,
+		withBuffer("Move to file", (*TaskBox).MoveToBuffer))
+	addCommand("open", "open file instead of current one [file]", inBrowse,
+		(*TaskBox).OpenPrompt)
+	addCommand("save-as", "save to another file [file]", inBrowse,
+		(*TaskBox).SaveAsPrompt)
+	addCommand("save-all", "save all files", inBrowse,
+		noArgsErr((*TaskBox).SaveAll))
+
+	// Common
+	addCommand("undo", "undo", inBrowse,
+		noArgs(func(tb *TaskBox) { tb.undo.Undo() }))
+	addCommand("redo", "redo", inBrowse,
+		noArgs(func(tb *TaskBox) { tb.undo.Redo() }))
+	addCommand("save", "save [to file]", inBrowse,
+		func(tb *TaskBox, args []string) error {
+			if len(args) == 0 {
+				return tb.Save(tb.path)
+			}
+			return tb.SaveAsPrompt(args)
+		})
+	addCommand("command", "command prompt", inAll,
+		noArgs((*TaskBox).CommandPrompt))
+	addCommand("help", "help", inBrowse,
+		noArgs(func(tb *TaskBox) {
+			tb.recordSteps()
+			help(tb.mode)
+		}))
+	addCommand("quit", "quit", inAll,
+		noArgs(func(tb *TaskBox) { tb.mode = modeExit }))
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"github.com/MakeNowJust/heredoc"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestExec(t *testing.T) {
+	tb := TaskBoxWithUndo()
+	tb.Lines = []string{"- [ ] Foo", "- [ ] Bar", "- [x] Baz"}
+	tb.undo.PutState()
+	tb.calculate()
+	tb.h = 3
+
+	assert.Nil(t, tb.Exec("down"))
+	assert.Nil(t, tb.Exec("toggle"))
+	assert.Nil(t, tb.Exec("move-up"))
+	assert.Equal(t, tb.String(), heredoc.Doc(`
+		> - [x] Bar
+		  - [ ] Foo
+		  - [x] Baz
+	`))
+	assert.Equal(t, "move-up", tb.lastCommand)
+
+	tb.undo.PutState()
+	assert.Nil(t, tb.Exec("undo"))
+	assert.Equal(t, tb.InnerString(), heredoc.Doc(`
+		- [ ] Foo
+		- [ ] Bar
+		- [x] Baz
+	`))
+}
+
+func TestExecErrors(t *testing.T) {
+	tb := TaskBoxFixture(3)
+
+	err := tb.Exec("foo")
+	assert.EqualError(t, err, "Unknown command: foo")
+
+	err = tb.Exec("task-mode")
+	assert.EqualError(t, err, "Command task-mode is not available in Task mode")
+
+	err = tb.Exec("toggle", "bar")
+	assert.EqualError(t, err, "Unexpected arguments: bar")
+
+	tb.mode = modeArchive
+	err = tb.Exec("toggle")
+	assert.EqualError(t, err, "Command toggle is not available in Archive mode")
+}
+
+func TestExecLine(t *testing.T) {
+	tb := &TaskBox{Lines: []string{"- [ ] Foo", "- [x] Bar"}}
+	tb.calculate()
+	tb.h = 3
+
+	assert.Nil(t, tb.ExecLine(""))
+	assert.Nil(t, tb.ExecLine("  filter   Closed "))
+	assert.Equal(t, tb.String(), heredoc.Doc(`
+		> - [x] Bar
+	`))
+	assert.Nil(t, tb.ExecLine("filter"))
+	assert.Equal(t, StatusAll, tb.filter)
+
+	err := tb.ExecLine("filter Foo")
+	assert.EqualError(t, err, "Unknown filter: Foo")
+}
+
+func TestCommandsRegistered(t *testing.T) {
+	assert.Equal(t, len(commands), len(commandNames))
+	for _, name := range commandNames {
+		c := commands[name]
+		assert.Equal(t, name, c.Name)
+		assert.NotEmpty(t, c.Desc)
+		assert.NotEmpty(t, c.Modes)
+	}
+}
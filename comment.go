@@ -14,7 +14,10 @@ func ParseComment(s string) string {
 	if lineTypeOf(s) != lineComment {
 		panic(fmt.Sprintf("Not a comment: %s", s))
 	}
-	return strings.TrimSpace(s[len(CommentPrefix) : len(s)-len(CommentSuffix)])
+	// Strip only spaces added by MakeComment to keep note indentation
+	s = s[len(CommentPrefix) : len(s)-len(CommentSuffix)]
+	s = strings.TrimPrefix(s, " ")
+	return strings.TrimSuffix(s, " ")
 }
 
 func MakeComment(s string) string {
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Config file in use. Macros are saved to it
+var configFile string
+
+// Path of file in taskbox config directory e.g. ~/.config/taskbox/keys
+func configPath(name string) string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = filepath.Join(os.Getenv("HOME"), ".config")
+	}
+	return filepath.Join(dir, "taskbox", name)
+}
+
+/*
+Config file has one "name value" pair per line:
+
+	# Color theme
+	theme light
+*/
+func LoadConfig(path string) (map[string]string, error) {
+	config := make(map[string]string)
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return config, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	n := 0
+	for scanner.Scan() {
+		n++
+		s := strings.TrimSpace(scanner.Text())
+		if s == "" || strings.HasPrefix(s, "#") {
+			continue
+		}
+		i := strings.IndexAny(s, " \t")
+		if i < 0 {
+			return nil, fmt.Errorf("%s:%d: Expected \"name value\"", path, n)
+		}
+		config[s[:i]] = strings.TrimSpace(s[i:])
+	}
+	return config, scanner.Err()
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"github.com/MakeNowJust/heredoc"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestLoadConfig(t *testing.T) {
+	file, _ := ioutil.TempFile("", "config")
+	defer os.Remove(file.Name())
+	ioutil.WriteFile(file.Name(), []byte(heredoc.Doc(`
+		# Comment
+		theme	light
+
+		foo  bar baz  
+	`)), 0644)
+
+	config, err := LoadConfig(file.Name())
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{
+		"theme": "light",
+		"foo":   "bar baz",
+	}, config)
+
+	config, err = LoadConfig(file.Name() + ".missing")
+	assert.Nil(t, err)
+	assert.Empty(t, config)
+
+	ioutil.WriteFile(file.Name(), []byte("theme\n"), 0644)
+	_, err = LoadConfig(file.Name())
+	assert.EqualError(t, err, file.Name()+":1: Expected \"name value\"")
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+/*
+Task may depend on other tasks given by ids:
+
+	- [ ] deploy blocked-by:k3x9qa,m2p7zz
+
+Task is blocked until all its blockers are closed or archived.
+Blocked tasks are dimmed. Actionable filter shows open tasks
+which are not blocked.
+*/
+
+// Ids of open tasks
+func (tb *TaskBox) openTaskIDs() map[string]bool {
+	ids := make(map[string]bool)
+	for _, s := range tb.Lines {
+		if lineTypeOf(s) == lineTask {
+			task := ParseTask(s)
+			if task.ID != "" && task.Status == StatusOpen {
+				ids[task.ID] = true
+			}
+		}
+	}
+	return ids
+}
+
+func (tb *TaskBox) openBlockers(task Task) []string {
+	var open []string
+	for _, id := range task.BlockedBy {
+		if tb.openIDs[id] {
+			open = append(open, id)
+		}
+	}
+	return open
+}
+
+// Is line an open task with open blockers
+func (tb *TaskBox) isBlocked(s string) bool {
+	if lineTypeOf(s) != lineTask {
+		return false
+	}
+	task := ParseTask(s)
+	return task.Status == StatusOpen && len(tb.openBlockers(task)) > 0
+}
+
+func blockedMessage(task Task, blockers []string) string {
+	return fmt.Sprintf("%s is blocked by %s",
+		task.Description, strings.Join(blockers, ","))
+}
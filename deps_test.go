@@ -0,0 +1,60 @@
+package main
+
+import (
+	"github.com/MakeNowJust/heredoc"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseBlockedBy(t *testing.T) {
+	assert.Nil(t, ParseTask("- [ ] foo").BlockedBy)
+	assert.Equal(t, []string{"abc", "def", "xyz"},
+		ParseTask("- [ ] foo blocked-by:abc,def blocked-by:xyz").BlockedBy)
+}
+
+func TestBlockedTasks(t *testing.T) {
+	tb := &TaskBox{Lines: []string{
+		"- [ ] migrate id:abc",
+		"- [ ] deploy blocked-by:abc",
+		"- [ ] announce blocked-by:abc,xyz",
+		"- [x] done id:xyz",
+	}}
+	tb.calculate()
+	tb.h = 5
+	assert.False(t, tb.isBlocked(tb.Lines[0]))
+	assert.True(t, tb.isBlocked(tb.Lines[1]))
+	assert.True(t, tb.isBlocked(tb.Lines[2]))
+
+	tb.Filter(StatusActionable)
+	assert.Equal(t, heredoc.Doc(`
+		> - [ ] migrate id:abc
+	`), tb.String())
+
+	tb.Filter(StatusAll)
+	tb.CursorDown()
+	tb.ToggleTask()
+	assert.Equal(t, "deploy blocked-by:abc is blocked by abc", tb.message)
+	assert.Equal(t, "- [x] deploy blocked-by:abc", tb.Lines[1])
+	assert.False(t, tb.isBlocked(tb.Lines[1]))
+
+	tb.message = ""
+	tb.cursor = 0
+	tb.ToggleTask()
+	assert.Equal(t, "", tb.message)
+	assert.False(t, tb.isBlocked(tb.Lines[2]))
+
+	tb.Filter(StatusActionable)
+	assert.Equal(t, heredoc.Doc(`
+		> - [ ] announce blocked-by:abc,xyz
+	`), tb.String())
+}
+
+func TestNextFilter(t *testing.T) {
+	tb := TaskBoxFixture(1)
+	var filters []string
+	for i := 0; i < 4; i++ {
+		tb.NextFilter()
+		filters = append(filters, tb.filter.String())
+	}
+	assert.Equal(t, []string{"Open", "Actionable", "Closed", "All"}, filters)
+}
@@ -3,11 +3,29 @@ package main
 import (
 	"github.com/nsf/termbox-go"
 	"github.com/smetana/editbox-go"
+	"strings"
 )
 
 func (tb *TaskBox) EnterEditMode() {
-	tb.mode = modeEdit
 	index, _ := tb.SelectedLine()
+	if tb.mode == modeAgenda || tb.mode == modeCalendar {
+		if index < 0 {
+			return
+		}
+		// Edit in file order. Agenda or calendar is restored on exit
+		tb.editFrom = tb.mode
+		tb.mode = modeEdit
+		tb.calculate()
+		tb.CursorToLine(index)
+	}
+	tb.mode = modeEdit
+	if tb.sorted {
+		// Edit in file order. Sorted view is restored on exit
+		tb.sorted = false
+		tb.sortedEdit = true
+		tb.calculate()
+		tb.CursorToLine(index)
+	}
 	if index < 0 {
 		tb.InsertLine(0, tb.TaskFilterPrefix())
 		tb.calculate()
@@ -26,29 +44,90 @@ func (tb *TaskBox) ExitEditMode() {
 			tb.DeleteLine(index)
 			tb.calculate()
 		}
+	} else if index == tb.addedNote && strings.TrimSpace(s) == "" {
+		// Note added by AddNote is left empty
+		tb.DeleteLine(index)
+		tb.showNotes = tb.notesShown
+		tb.calculate()
+		tb.cursorToBlock(index - 1)
 	}
+	tb.addedNote = 0
 	termbox.HideCursor()
 	tb.mode = modeTask
+	if tb.editFrom != modeTask {
+		index, _ := tb.SelectedLine()
+		tb.mode = tb.editFrom
+		tb.editFrom = modeTask
+		tb.calculate()
+		tb.CursorToLine(index)
+	}
+	if tb.sortedEdit {
+		tb.sortedEdit = false
+		tb.ToggleSortedView()
+	}
 }
 
 // Attach editor at cursor
 func (tb *TaskBox) AttachEditor() {
 	_, s := tb.SelectedLine()
-	tb.editor = editbox.Input(tb.x+2, tb.CursorToY(), tb.w-3, 0, 0)
+	tb.newEditor(s)
+	tb.editLine = s
+}
+
+// Editor covers all rows of wrapped line
+func (tb *TaskBox) newEditor(s string) {
+	if rows := len(tb.wrap(s)); rows > 1 {
+		tb.editor = editbox.NewEditbox(tb.x+2, tb.CursorToY(), tb.w-3, rows,
+			editbox.Options{Wrap: true, Fg: theme.Editor.Fg, Bg: theme.Editor.Bg})
+	} else {
+		tb.editor = editbox.Input(tb.x+2, tb.CursorToY(), tb.w-3,
+			theme.Editor.Fg, theme.Editor.Bg)
+	}
 	tb.editor.SetText(s)
 }
 
+// Update edited line and recreate editor if line is wrapped
+// to different number of rows
+func (tb *TaskBox) fitEditor(oldL string) {
+	index, _ := tb.SelectedLine()
+	s := tb.editor.Text()
+	tb.UpdateLine(index, s)
+	if len(tb.wrap(s)) == len(tb.wrap(oldL)) {
+		return
+	}
+	x, y := tb.editor.GetCursor()
+	tb.scrollToCursor()
+	tb.newEditor(s)
+	tb.editor.SetCursor(x, y)
+	tb.editor.Render()
+}
+
 func (tb *TaskBox) DetachEditor() {
 	index, _ := tb.SelectedLine()
 	tb.UpdateLine(index, tb.editor.Text())
 	tb.editor = nil
+	// Task without description is not created yet
+	if lineTypeOf(tb.editLine) != lineTask ||
+		ParseTask(tb.editLine).Description == "" {
+		tb.taskCreated(index)
+	}
+}
+
+// Give id to new task and log it
+func (tb *TaskBox) taskCreated(i int) {
+	s := tb.Lines[i]
+	if lineTypeOf(s) != lineTask || ParseTask(s).Description == "" {
+		return
+	}
+	tb.autoID(i)
+	tb.logEvent(EventCreated, tb.Lines[i])
 }
 
 func (tb *TaskBox) EditEnterKey() {
 	pos, _ := tb.editor.GetCursor()
 	tb.DetachEditor()
 	i, _ := tb.SelectedLine()
-	tb.SplitLine(i, pos)
+	tb.taskCreated(tb.SplitLine(i, pos))
 	tb.calculate()
 	tb.CursorDown()
 	tb.AttachEditor()
@@ -80,7 +159,7 @@ func (tb *TaskBox) EditBackspaceKey(ev termbox.Event) {
 		}
 		s := tb.editor.Text()
 		tb.DetachEditor()
-		tb.DeleteLine(i)
+		tb.removeLine(i)
 		tb.calculate()
 		tb.CursorUp()
 		tb.AttachEditor()
@@ -88,7 +167,8 @@ func (tb *TaskBox) EditBackspaceKey(ev termbox.Event) {
 		tb.editor.SetCursor(len(tb.editor.Text())-len(s), 0)
 	} else {
 		ln := len(TaskPrefix)
-		if pos == ln && lineTypeOf(tb.editor.Text()) == lineTask {
+		oldL := tb.editor.Text()
+		if pos == ln && lineTypeOf(oldL) == lineTask {
 			for i := 0; i < ln; i++ {
 				tb.editor.HandleEvent(ev)
 			}
@@ -96,6 +176,7 @@ func (tb *TaskBox) EditBackspaceKey(ev termbox.Event) {
 			tb.editor.HandleEvent(ev)
 		}
 		tb.editor.Render()
+		tb.fitEditor(oldL)
 	}
 }
 
@@ -141,36 +222,17 @@ func (tb *TaskBox) EditMovePageUp() {
 }
 
 func (tb *TaskBox) HandleEditEvent(ev termbox.Event) {
-	switch {
-	case ev.Key == termbox.KeyEsc:
-		tb.ExitEditMode()
-	case ev.Key == termbox.KeyEnter:
-		tb.EditEnterKey()
-	case ev.Key == termbox.KeyTab:
-		tb.AddTaskPrefix()
-	case ev.Key == termbox.KeyBackspace || ev.Key == termbox.KeyBackspace2:
+	if ev.Key == termbox.KeyBackspace || ev.Key == termbox.KeyBackspace2 {
 		tb.EditBackspaceKey(ev)
-	case ev.Key == termbox.KeyArrowDown:
-		tb.EditMoveDown()
-	case ev.Key == termbox.KeyArrowUp:
-		tb.EditMoveUp()
-	case ev.Key == termbox.KeyPgdn:
-		tb.EditMovePageDown()
-	case ev.Key == termbox.KeyPgup:
-		tb.EditMovePageUp()
-	case ev.Key == termbox.KeyCtrlQ ||
-		ev.Key == termbox.KeyCtrlX ||
-		ev.Key == termbox.KeyCtrlC:
-		tb.mode = modeExit
-	default:
-		index, oldL := tb.SelectedLine()
+	} else if !tb.HandleKey(ev) {
+		_, oldL := tb.SelectedLine()
 		tb.editor.HandleEvent(ev)
 		// TODO Investigate why we need to render editor
 		// to get correct cursor position
 		tb.editor.Render()
 		tb.lastX, _ = tb.editor.GetCursor()
 		if oldL != tb.editor.Text() {
-			tb.UpdateLine(index, tb.editor.Text())
+			tb.fitEditor(oldL)
 			tb.modified = true
 		}
 	}
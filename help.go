@@ -0,0 +1,119 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+)
+
+/*
+Help is generated from key bindings of all modes, key sequences of
+task mode (see keyseq.go and macro.go) and command line flags.
+Bindings for the mode help was opened from are listed first. Headings
+are not indented so help can be searched with searchLines
+*/
+
+var sequenceHelp = [][2]string{
+	{"5j", "repeat key 5 times"},
+	{"gg G", "first line, last line"},
+	{"5G", "go to line 5"},
+	{"ma 'a", "set mark a, jump to mark a"},
+	{".", "repeat last change"},
+	{"Qa Q", "record macro a, stop recording"},
+	{"@a @@", "play macro a, play last macro"},
+}
+
+func helpLines(m mode, flags *flag.FlagSet) []string {
+	modes := []mode{m}
+	for _, km := range keymapModes {
+		if km != m {
+			modes = append(modes, km)
+		}
+	}
+	var lines []string
+	for _, hm := range modes {
+		var rows [][2]string
+		for _, kh := range keymap.Help(hm) {
+			rows = append(rows, [2]string{kh.KeysString(), kh.Desc})
+		}
+		lines = append(lines, hm.String()+" mode")
+		lines = append(lines, helpRows(rows)...)
+		lines = append(lines, "")
+		if hm == modeTask {
+			lines = append(lines, "Task mode sequences")
+			lines = append(lines, helpRows(sequenceHelp)...)
+			lines = append(lines, "")
+		}
+	}
+	var rows [][2]string
+	flags.VisitAll(func(f *flag.Flag) {
+		usage := f.Usage
+		if f.DefValue != "" {
+			usage += " (default " + f.DefValue + ")"
+		}
+		rows = append(rows, [2]string{"-" + f.Name, usage})
+	})
+	lines = append(lines, "Command line flags")
+	return append(lines, helpRows(rows)...)
+}
+
+// Longer keys do not align descriptions
+const helpKeysWidth = 12
+
+// Indented rows with aligned descriptions
+func helpRows(rows [][2]string) []string {
+	w := 0
+	for _, r := range rows {
+		if len(r[0]) > w {
+			w = len(r[0])
+		}
+	}
+	if w > helpKeysWidth {
+		w = helpKeysWidth
+	}
+	lines := make([]string, len(rows))
+	for i, r := range rows {
+		lines[i] = fmt.Sprintf("  %-*s  %s", w, r[0], r[1])
+	}
+	return lines
+}
+
+// Lines containing query (case insensitive) with their headings.
+// All lines of matching heading are kept
+func searchLines(lines []string, query string) []string {
+	if query == "" {
+		return lines
+	}
+	q := strings.ToLower(query)
+	match := func(s string) bool {
+		return strings.Contains(strings.ToLower(s), q)
+	}
+	var found []string
+	var heading string
+	headingMatch, headingShown := false, false
+	for _, s := range lines {
+		switch {
+		case s == "":
+		case !strings.HasPrefix(s, " "):
+			heading, headingMatch, headingShown = s, match(s), false
+			if headingMatch {
+				found = appendHeading(found, heading)
+				headingShown = true
+			}
+		case headingMatch || match(s):
+			if !headingShown {
+				found = appendHeading(found, heading)
+				headingShown = true
+			}
+			found = append(found, s)
+		}
+	}
+	return found
+}
+
+func appendHeading(lines []string, heading string) []string {
+	if len(lines) > 0 {
+		lines = append(lines, "")
+	}
+	return append(lines, heading)
+}
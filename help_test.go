@@ -0,0 +1,54 @@
+package main
+
+import (
+	"flag"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestHelpLines(t *testing.T) {
+	flags := flag.NewFlagSet("taskbox", flag.ContinueOnError)
+	flags.String("theme", "", "Color theme")
+	flags.Int("autosave", 0, "Autosave interval in minutes")
+	lines := helpLines(modeArchive, flags)
+
+	assert.Equal(t, "Archive mode", lines[0])
+	assert.Equal(t, "  k,Up          cursor up", lines[1])
+	assert.Contains(t, lines, "  PgUp          page up")
+	assert.Contains(t, lines, "  q,Ctrl+q,Ctrl+x,Ctrl+c  quit")
+	assert.Contains(t, lines, "Task mode")
+	assert.Contains(t, lines, "  Enter,End,a   edit")
+	assert.Contains(t, lines, "  ma 'a  set mark a, jump to mark a")
+	assert.Equal(t, []string{
+		"Command line flags",
+		"  -autosave  Autosave interval in minutes (default 0)",
+		"  -theme     Color theme",
+	}, lines[len(lines)-3:])
+}
+
+func TestSearchLines(t *testing.T) {
+	lines := []string{
+		"Task mode",
+		"  k,Up  cursor up",
+		"     d  delete line",
+		"",
+		"Edit mode",
+		"  Up  edit line above",
+		"",
+		"Flags",
+		"  -theme  Color theme",
+	}
+	assert.Equal(t, lines, searchLines(lines, ""))
+	assert.Equal(t, []string{
+		"Task mode",
+		"  k,Up  cursor up",
+		"",
+		"Edit mode",
+		"  Up  edit line above",
+	}, searchLines(lines, "UP"))
+	assert.Equal(t, []string{
+		"Flags",
+		"  -theme  Color theme",
+	}, searchLines(lines, "flags"))
+	assert.Empty(t, searchLines(lines, "foo"))
+}
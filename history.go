@@ -0,0 +1,266 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+/*
+Task events are kept in sidecar log next to task file
+(TODO.md.history for TODO.md). Each event is one CSV record:
+
+	time,event,key,description
+
+Key identifies the task. See taskKey
+*/
+
+const historyExt = ".history"
+
+const (
+	EventCreated    = "created"
+	EventCompleted  = "completed"
+	EventReopened   = "reopened"
+	EventArchived   = "archived"
+	EventUnarchived = "unarchived"
+	EventDeleted    = "deleted"
+)
+
+type HistoryEntry struct {
+	Time        time.Time
+	Event       string
+	Key         string
+	Description string
+}
+
+func AppendHistory(path string, e HistoryEntry) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := csv.NewWriter(f)
+	w.Write([]string{e.Time.Format(time.RFC3339), e.Event, e.Key, e.Description})
+	w.Flush()
+	return w.Error()
+}
+
+// Read history log. Missing file is ok
+func LoadHistory(path string) ([]HistoryEntry, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = 4
+	var entries []HistoryEntry
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		var e HistoryEntry
+		if e.Time, err = time.Parse(time.RFC3339, rec[0]); err != nil {
+			return nil, err
+		}
+		e.Event, e.Key, e.Description = rec[1], rec[2], rec[3]
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+/*
+Events are written to history log when task file is saved, so changes
+which are undone or never saved leave no events. Events are kept in
+undo state which they lead to. Undo drops events which are not saved
+yet and makes opposite events for saved ones, redo makes them again.
+*/
+type pendingEvent struct {
+	HistoryEntry
+	path string // task file
+}
+
+var oppositeEvents = map[string]string{
+	EventCreated:    EventDeleted,
+	EventDeleted:    EventCreated,
+	EventCompleted:  EventReopened,
+	EventReopened:   EventCompleted,
+	EventArchived:   EventUnarchived,
+	EventUnarchived: EventArchived,
+}
+
+// Record event for task line. Archived task lines are ok.
+// Tasks without description and lines of TaskBox without file
+// have no history
+func (tb *TaskBox) logEvent(event, s string) {
+	tb.logFileEvent(tb.path, event, s)
+}
+
+// Record event for task line of another open file
+func (tb *TaskBox) logFileEvent(path, event, s string) {
+	s = lineContent(s)
+	if path == "" || lineTypeOf(s) != lineTask {
+		return
+	}
+	task := ParseTask(s)
+	if task.Description == "" {
+		return
+	}
+	tb.addEvent(&pendingEvent{
+		HistoryEntry: HistoryEntry{
+			Time:        time.Now(),
+			Event:       event,
+			Key:         taskKey(task),
+			Description: task.Description,
+		},
+		path: path,
+	})
+}
+
+// Delete line and record deleted task. Lines removed by any command
+// go through here so history does not miss them
+func (tb *TaskBox) removeLine(i int) string {
+	s := tb.DeleteLine(i)
+	tb.logEvent(EventDeleted, s)
+	return s
+}
+
+func (tb *TaskBox) addEvent(e *pendingEvent) {
+	tb.events = append(tb.events, e)
+	if tb.undo != nil {
+		tb.stateEvents = append(tb.stateEvents, e)
+	}
+}
+
+// Remove event which is not saved yet. Return false if it is saved
+func (tb *TaskBox) dropEvent(e *pendingEvent) bool {
+	for i, p := range tb.events {
+		if p == e {
+			tb.events = append(tb.events[:i], tb.events[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Forget unsaved events of file
+func (tb *TaskBox) dropEvents(path string) {
+	var events []*pendingEvent
+	for _, e := range tb.events {
+		if e.path != path {
+			events = append(events, e)
+		}
+	}
+	tb.events = events
+}
+
+// Undo events. Return opposite events made for saved ones
+func (tb *TaskBox) revertEvents(events []*pendingEvent) []*pendingEvent {
+	undone := make([]*pendingEvent, len(events))
+	for i := len(events) - 1; i >= 0; i-- {
+		if tb.dropEvent(events[i]) {
+			continue
+		}
+		e := *events[i]
+		e.Time = time.Now()
+		e.Event = oppositeEvents[e.Event]
+		undone[i] = &e
+		tb.events = append(tb.events, &e)
+	}
+	return undone
+}
+
+// Redo events undone by revertEvents
+func (tb *TaskBox) replayEvents(events, undone []*pendingEvent) {
+	for i, e := range events {
+		if i < len(undone) && undone[i] != nil {
+			if tb.dropEvent(undone[i]) {
+				continue // Saved event stands
+			}
+			redo := *e
+			e = &redo
+			events[i] = e
+		}
+		e.Time = time.Now()
+		tb.events = append(tb.events, e)
+	}
+}
+
+// Write events of file saved as another one to history log
+func (tb *TaskBox) saveEvents(from, to string) error {
+	var events []*pendingEvent
+	for _, e := range tb.events {
+		if e.path != from {
+			events = append(events, e)
+			continue
+		}
+		if err := AppendHistory(sidecarPath(to, historyExt), e.HistoryEntry); err != nil {
+			return err
+		}
+	}
+	tb.events = events
+	return nil
+}
+
+// Saved and unsaved events of task file
+func (tb *TaskBox) history() ([]HistoryEntry, error) {
+	entries, err := LoadHistory(sidecarPath(tb.path, historyExt))
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range tb.events {
+		if e.path == tb.path {
+			entries = append(entries, e.HistoryEntry)
+		}
+	}
+	return entries, nil
+}
+
+// Events of task under cursor
+func (tb *TaskBox) TaskHistory() ([]HistoryEntry, error) {
+	_, s := tb.SelectedLine()
+	s = lineContent(s)
+	if lineTypeOf(s) != lineTask {
+		return nil, fmt.Errorf("Not a task")
+	}
+	entries, err := tb.history()
+	if err != nil {
+		return nil, err
+	}
+	key := taskKey(ParseTask(s))
+	var history []HistoryEntry
+	for _, e := range entries {
+		if e.Key == key {
+			history = append(history, e)
+		}
+	}
+	return history, nil
+}
+
+func (tb *TaskBox) ShowHistory() error {
+	history, err := tb.TaskHistory()
+	if err != nil {
+		return err
+	}
+	if len(history) == 0 {
+		tb.message = "No history"
+		return nil
+	}
+	items := make([]string, len(history))
+	for i, e := range history {
+		items[i] = fmt.Sprintf("%s  %-10s  %s",
+			e.Time.Local().Format("2006-01-02 15:04"), e.Event, e.Description)
+	}
+	tb.recordSteps()
+	chooseItem("History", items, len(items)-1)
+	return nil
+}
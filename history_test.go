@@ -0,0 +1,126 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHistory(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "history")
+	defer os.RemoveAll(dir)
+	tb := &TaskBox{Lines: []string{"- [ ] foo", "- [ ] bar", "- [ ] "}}
+	tb.path = filepath.Join(dir, "TODO.md")
+	tb.calculate()
+
+	tb.ToggleTask()
+	tb.ToggleTask()
+	tb.ToggleComment()
+	tb.EnterArchiveMode()
+	tb.ToggleComment()
+	tb.mode = modeTask
+	tb.calculate()
+	tb.CursorDown()
+	tb.TaskDeleteKey()
+	tb.TaskDeleteKey() // no description
+
+	// Events are written on save
+	entries, err := LoadHistory(filepath.Join(dir, "TODO.md.history"))
+	assert.Nil(t, err)
+	assert.Empty(t, entries)
+	assert.Nil(t, tb.Save(tb.path))
+	entries, err = LoadHistory(filepath.Join(dir, "TODO.md.history"))
+	assert.Nil(t, err)
+	var events []string
+	for _, e := range entries {
+		events = append(events, e.Event+" "+e.Key)
+	}
+	assert.Equal(t, []string{
+		"completed foo",
+		"reopened foo",
+		"archived foo",
+		"unarchived foo",
+		"deleted bar",
+	}, events)
+
+	tb.cursor = 0
+	history, err := tb.TaskHistory()
+	assert.Nil(t, err)
+	assert.Equal(t, 4, len(history))
+}
+
+func TestHistoryWithoutFile(t *testing.T) {
+	tb := TaskBoxFixture(1)
+	tb.Lines[0] = "- [ ] foo"
+	tb.ToggleTask()
+	history, err := tb.TaskHistory()
+	assert.Nil(t, err)
+	assert.Empty(t, history)
+}
+
+func TestHistoryUndo(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "history")
+	defer os.RemoveAll(dir)
+	tb := &TaskBox{Lines: []string{"- [ ] foo"}}
+	tb.path = filepath.Join(dir, "TODO.md")
+	tb.undo = NewUndo(tb)
+	tb.calculate()
+	events := func() []string {
+		entries, err := LoadHistory(filepath.Join(dir, "TODO.md.history"))
+		assert.Nil(t, err)
+		var events []string
+		for _, e := range entries {
+			events = append(events, e.Event)
+		}
+		return events
+	}
+
+	// Unsaved event is dropped
+	tb.ToggleTask()
+	tb.undo.PutState()
+	tb.undo.Undo()
+	assert.Nil(t, tb.Save(tb.path))
+	assert.Empty(t, events())
+
+	// Redo makes it again
+	tb.undo.Redo()
+	assert.Nil(t, tb.Save(tb.path))
+	assert.Equal(t, []string{"completed"}, events())
+
+	// Saved event gets opposite one
+	tb.undo.Undo()
+	assert.Nil(t, tb.Save(tb.path))
+	assert.Equal(t, []string{"completed", "reopened"}, events())
+
+	// Unsaved opposite event is dropped on redo
+	tb.undo.Redo()
+	tb.undo.Undo()
+	tb.undo.Redo()
+	assert.Nil(t, tb.Save(tb.path))
+	assert.Equal(t, []string{"completed", "reopened", "completed"}, events())
+}
+
+func TestHistoryRemovedLines(t *testing.T) {
+	tb, dir := BuffersFixture(t)
+	defer os.RemoveAll(dir)
+	events := func(tb *TaskBox) []string {
+		entries, err := tb.history()
+		assert.Nil(t, err)
+		var events []string
+		for _, e := range entries {
+			events = append(events, e.Event+" "+e.Key)
+		}
+		return events
+	}
+
+	assert.Nil(t, tb.Cut(defaultRegister))
+	assert.Nil(t, tb.Paste(defaultRegister, false))
+	assert.Nil(t, tb.MoveToBuffer(1))
+	assert.Equal(t, []string{"deleted Foo", "created Foo", "deleted Foo"},
+		events(tb))
+
+	assert.Nil(t, tb.SwitchBuffer(1))
+	assert.Equal(t, []string{"created Foo"}, events(tb))
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"crypto/rand"
+	"fmt"
+)
+
+/*
+Tasks may have stable ids written as id:k3x9qa token. New tasks get
+ids when "ids on" is set in config file, existing tasks get ids with
+add-id command. Copies of tasks get new ids. Task id is its key in
+history and time logs so they survive edits and moves.
+*/
+
+var autoIDs bool
+
+const (
+	idLength = 6
+	idChars  = "0123456789abcdefghijklmnopqrstuvwxyz"
+)
+
+func newID() string {
+	b := make([]byte, idLength)
+	_, err := rand.Read(b)
+	check(err)
+	for i := range b {
+		b[i] = idChars[int(b[i])%len(idChars)]
+	}
+	return string(b)
+}
+
+// Ids of tasks in all buffers including archived ones
+func (tb *TaskBox) taskIDs() map[string]bool {
+	ids := make(map[string]bool)
+	for _, lines := range tb.allLines() {
+		for _, s := range lines {
+			if s = lineContent(s); lineTypeOf(s) == lineTask {
+				if id := ParseTask(s).ID; id != "" {
+					ids[id] = true
+				}
+			}
+		}
+	}
+	return ids
+}
+
+func (tb *TaskBox) uniqueID() string {
+	ids := tb.taskIDs()
+	for {
+		if id := newID(); !ids[id] {
+			return id
+		}
+	}
+}
+
+// Task line with new id (without id if id is "").
+// Other lines are returned as is
+func setLineID(s, id string) string {
+	content := lineContent(s)
+	if lineTypeOf(content) != lineTask {
+		return s
+	}
+	task := ParseTask(content)
+	task.SetID(id)
+	if lineTypeOf(s) == lineComment {
+		return MakeComment(task.String())
+	}
+	return task.String()
+}
+
+func lineID(s string) string {
+	if s = lineContent(s); lineTypeOf(s) == lineTask {
+		return ParseTask(s).ID
+	}
+	return ""
+}
+
+// Give new id to task line which has id already
+func (tb *TaskBox) renewID(i int) {
+	if lineID(tb.Lines[i]) != "" {
+		tb.UpdateLine(i, setLineID(tb.Lines[i], tb.uniqueID()))
+	}
+}
+
+// Give id to new task if ids are on
+func (tb *TaskBox) autoID(i int) {
+	if autoIDs && lineID(tb.Lines[i]) == "" {
+		tb.UpdateLine(i, setLineID(tb.Lines[i], tb.uniqueID()))
+	}
+}
+
+// Give ids to selected tasks without id
+func (tb *TaskBox) AddID() {
+	for _, i := range tb.Selection() {
+		if s := lineContent(tb.Lines[i]); lineTypeOf(s) == lineTask &&
+			lineID(s) == "" {
+			tb.UpdateLine(i, setLineID(tb.Lines[i], tb.uniqueID()))
+		}
+	}
+	tb.ClearSelection()
+	tb.calculate()
+}
+
+// Index of task line with id. -1 if there is no such task
+func (tb *TaskBox) FindTask(id string) int {
+	for i, s := range tb.Lines {
+		if lineID(s) == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// Move cursor to task with id. Mode and filters are changed if needed
+func (tb *TaskBox) GotoTask(id string) error {
+	if id == "" {
+		return fmt.Errorf("No task id")
+	}
+	i := tb.FindTask(id)
+	if i < 0 {
+		return fmt.Errorf("No task id:%s", id)
+	}
+	if lineTypeOf(tb.Lines[i]) == lineComment {
+		tb.mode = modeArchive
+	} else {
+		tb.mode = modeTask
+		if !tb.inFilter(tb.Lines[i]) {
+			tb.filter = StatusAll
+			tb.tag = ""
+		}
+	}
+	tb.ClearSelection()
+	tb.calculate()
+	tb.CursorToLine(i)
+	return nil
+}
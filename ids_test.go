@@ -0,0 +1,103 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestSetID(t *testing.T) {
+	task := ParseTask("- [ ] foo")
+	assert.Equal(t, "", task.ID)
+	task.SetID("abc123")
+	assert.Equal(t, "foo id:abc123", task.Description)
+	assert.Equal(t, "abc123", ParseTask(task.String()).ID)
+	task.SetID("xyz")
+	assert.Equal(t, "foo id:xyz", task.Description)
+	task.SetID("")
+	assert.Equal(t, "foo", task.Description)
+
+	task = ParseTask("- [ ] id:abc foo")
+	task.SetID("")
+	assert.Equal(t, "foo", task.Description)
+}
+
+func TestNewID(t *testing.T) {
+	id := newID()
+	assert.Regexp(t, "^[0-9a-z]{6}$", id)
+	assert.NotEqual(t, id, newID())
+}
+
+func TestCopyRenewsID(t *testing.T) {
+	tb := &TaskBox{Lines: []string{"- [ ] foo id:abc", "- [ ] bar"}}
+	tb.calculate()
+	tb.CopyLine()
+	assert.Equal(t, "- [ ] foo id:abc", tb.Lines[1])
+	assert.NotEqual(t, "abc", lineID(tb.Lines[0]))
+	assert.Equal(t, "- [ ] foo", tb.Lines[0][:9])
+
+	tb.CursorDown()
+	tb.CursorDown()
+	tb.CopyLine()
+	assert.Equal(t, "- [ ] bar", tb.Lines[2])
+}
+
+func TestSplitKeepsID(t *testing.T) {
+	tb := &TaskBox{Lines: []string{"- [ ] foo id:abc bar"}}
+	tb.calculate()
+	i := tb.SplitLine(0, len("- [ ] foo "))
+	assert.Equal(t, 1, i)
+	assert.Equal(t, []string{"- [ ] foo id:abc", "- [ ] bar"}, tb.Lines)
+
+	tb.Lines = []string{"- [ ] foo bar id:abc"}
+	tb.SplitLine(0, len("- [ ] foo "))
+	assert.Equal(t, []string{"- [ ] foo id:abc", "- [ ] bar"}, tb.Lines)
+}
+
+func TestAutoID(t *testing.T) {
+	tb := &TaskBox{Lines: []string{"- [ ] foo", "bar"}}
+	tb.calculate()
+	tb.autoID(0)
+	assert.Equal(t, "- [ ] foo", tb.Lines[0])
+	autoIDs = true
+	defer func() { autoIDs = false }()
+	tb.autoID(0)
+	tb.autoID(1)
+	assert.NotEqual(t, "", lineID(tb.Lines[0]))
+	assert.Equal(t, "bar", tb.Lines[1])
+}
+
+func TestAddIDAndGoto(t *testing.T) {
+	tb := &TaskBox{Lines: []string{
+		"- [ ] foo",
+		"- [x] bar id:abc",
+		"<!-- - [ ] baz id:xyz -->",
+	}}
+	tb.filter = StatusOpen
+	tb.calculate()
+	tb.AddID()
+	id := lineID(tb.Lines[0])
+	assert.NotEqual(t, "", id)
+	assert.Equal(t, map[string]bool{id: true, "abc": true, "xyz": true},
+		tb.taskIDs())
+
+	assert.Nil(t, tb.GotoTask("abc"))
+	assert.Equal(t, StatusAll, tb.filter)
+	i, _ := tb.SelectedLine()
+	assert.Equal(t, 1, i)
+
+	assert.Nil(t, tb.GotoTask("xyz"))
+	assert.Equal(t, modeArchive, tb.mode)
+	i, _ = tb.SelectedLine()
+	assert.Equal(t, 2, i)
+
+	assert.EqualError(t, tb.GotoTask("foo"), "No task id:foo")
+	assert.EqualError(t, tb.Exec("goto", "id:"), "No task id")
+
+	// Tag filter hiding the task is cleared
+	tb.mode = modeTask
+	tb.FilterTag("work")
+	assert.Nil(t, tb.GotoTask("abc"))
+	assert.Equal(t, "", tb.tag)
+	i, _ = tb.SelectedLine()
+	assert.Equal(t, 1, i)
+}
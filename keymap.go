@@ -0,0 +1,395 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/nsf/termbox-go"
+	"io"
+	"os"
+	"strings"
+)
+
+/*
+Key bindings are stored in plain text file, one binding per line:
+
+	# mode  key     command [args]
+	task    x       toggle
+	task    Ctrl+d  delete
+	task    F       filter Closed
+	archive Esc     task-mode
+	task    c       -
+
+"-" removes default binding. Bindings from the file are applied on
+top of the default ones below.
+*/
+const defaultKeys = `
+task     k        up
+task     Up       up
+task     j        down
+task     Down     down
+task     PgUp     page-up
+task     PgDn     page-down
+task     Enter    edit
+task     End      edit
+task     a        edit
+task     i        insert
+task     Ins      insert
+task     d        delete
+task     Del      delete
+task     Space    toggle
+task     h        move-up
+task     Left     move-up
+task     l        move-down
+task     Right    move-down
+task     Ctrl+l   move-bottom
+task     c        copy
+task     z        archive
+task     v        visual
+task     t        mark
+task     Esc      clear-selection
+task     y        yank
+task     x        cut
+task     p        paste
+task     P        paste-before
+task     Y        yank +
+task     Ctrl+v   paste +
+task     +        priority-up
+task     =        priority-up
+task     -        priority-down
+task     o        sort-view
+task     O        sort
+task     T        timer
+task     n        add-note
+task     N        notes
+task     H        history
+task     %        stats
+task     f        filter
+task     b        buffer
+task     Tab      buffer-next
+task     ]        buffer-next
+task     [        buffer-prev
+task     M        move-to
+task     Ctrl+f   archive-mode
+task     A        agenda-mode
+task     C        calendar-mode
+task     u        undo
+task     r        redo
+task     :        command
+task     ?        help
+task     s        save
+task     w        save
+task     Ctrl+s   save
+task     S        save-all
+task     W        save-as
+task     Ctrl+o   open
+task     q        quit
+task     Ctrl+q   quit
+task     Ctrl+x   quit
+task     Ctrl+c   quit
+
+edit     Esc      stop-edit
+edit     Enter    split
+edit     Tab      task-prefix
+edit     Up       edit-up
+edit     Down     edit-down
+edit     PgUp     edit-page-up
+edit     PgDn     edit-page-down
+edit     Ctrl+p   command
+edit     Ctrl+q   quit
+edit     Ctrl+x   quit
+edit     Ctrl+c   quit
+
+archive  k        up
+archive  Up       up
+archive  j        down
+archive  Down     down
+archive  PgUp     page-up
+archive  PgDn     page-down
+archive  z        archive
+archive  c        copy
+archive  N        notes
+archive  H        history
+archive  Esc      task-mode
+archive  Ctrl+f   task-mode
+archive  u        undo
+archive  r        redo
+archive  :        command
+archive  ?        help
+archive  s        save
+archive  w        save
+archive  Ctrl+s   save
+archive  S        save-all
+archive  Ctrl+o   open
+archive  b        buffer
+archive  Tab      buffer-next
+archive  ]        buffer-next
+archive  [        buffer-prev
+archive  q        quit
+archive  Ctrl+q   quit
+archive  Ctrl+x   quit
+archive  Ctrl+c   quit
+
+agenda   k        up
+agenda   Up       up
+agenda   j        down
+agenda   Down     down
+agenda   PgUp     page-up
+agenda   PgDn     page-down
+agenda   Enter    edit
+agenda   Space    toggle
+agenda   +        priority-up
+agenda   =        priority-up
+agenda   -        priority-down
+agenda   f        filter
+agenda   T        timer
+agenda   H        history
+agenda   Esc      task-mode
+agenda   A        task-mode
+agenda   u        undo
+agenda   r        redo
+agenda   :        command
+agenda   ?        help
+agenda   s        save
+agenda   w        save
+agenda   Ctrl+s   save
+agenda   S        save-all
+agenda   Ctrl+o   open
+agenda   b        buffer
+agenda   Tab      buffer-next
+agenda   ]        buffer-next
+agenda   [        buffer-prev
+agenda   q        quit
+agenda   Ctrl+q   quit
+agenda   Ctrl+x   quit
+agenda   Ctrl+c   quit
+
+calendar Left     prev-day
+calendar h        prev-day
+calendar Right    next-day
+calendar l        next-day
+calendar Up       prev-week
+calendar k        prev-week
+calendar Down     next-week
+calendar j        next-week
+calendar PgUp     prev-month
+calendar PgDn     next-month
+calendar .        today
+calendar K        up
+calendar J        down
+calendar Enter    edit
+calendar Space    toggle
+calendar <        reschedule -1
+calendar >        reschedule 1
+calendar {        reschedule -7
+calendar }        reschedule 7
+calendar f        filter
+calendar H        history
+calendar Esc      task-mode
+calendar C        task-mode
+calendar u        undo
+calendar r        redo
+calendar :        command
+calendar ?        help
+calendar s        save
+calendar S        save-all
+calendar Ctrl+o   open
+calendar b        buffer
+calendar Tab      buffer-next
+calendar q        quit
+calendar Ctrl+q   quit
+calendar Ctrl+x   quit
+calendar Ctrl+c   quit
+`
+
+// Modes which may have key bindings
+var keymapModes = []mode{modeTask, modeEdit, modeArchive, modeAgenda,
+	modeCalendar}
+
+type Binding struct {
+	Mode    mode
+	Key     Key
+	Command string // Command line. Empty to unbind
+}
+
+type Keymap struct {
+	Bindings []Binding
+}
+
+var keymap *Keymap
+
+func init() {
+	// Commands are registered by init() in commands.go.
+	// Register new commands there, not in init() of other files
+	keymap = DefaultKeymap()
+}
+
+func DefaultKeymap() *Keymap {
+	bindings, err := ParseKeymap(strings.NewReader(defaultKeys))
+	check(err)
+	km := &Keymap{}
+	km.Apply(bindings)
+	return km
+}
+
+// Load default keymap with overrides from file. Missing file is ok
+func LoadKeymap(path string) (*Keymap, error) {
+	km := DefaultKeymap()
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return km, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	bindings, err := ParseKeymap(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%v", path, err)
+	}
+	km.Apply(bindings)
+	return km, nil
+}
+
+func parseMode(s string) (mode, error) {
+	for _, m := range keymapModes {
+		if strings.EqualFold(m.String(), s) {
+			return m, nil
+		}
+	}
+	return modeExit, fmt.Errorf("Unknown mode: %s", s)
+}
+
+func ParseKeymap(r io.Reader) ([]Binding, error) {
+	var bindings []Binding
+	defined := make(map[Binding]int) // binding without command -> line
+	scanner := bufio.NewScanner(r)
+	n := 0
+	for scanner.Scan() {
+		n++
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if len(fields) < 3 {
+			return nil, fmt.Errorf("%d: Expected \"mode key command\"", n)
+		}
+		m, err := parseMode(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("%d: %v", n, err)
+		}
+		k, err := ParseKey(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("%d: %v", n, err)
+		}
+		if m == modeEdit && k.IsChar() {
+			return nil, fmt.Errorf("%d: Can not bind %s in Edit mode", n, k)
+		}
+		b := Binding{Mode: m, Key: k}
+		if prev, ok := defined[b]; ok {
+			return nil, fmt.Errorf("%d: %s in %s mode is already bound on line %d",
+				n, k, m, prev)
+		}
+		defined[b] = n
+		if fields[2] != "-" {
+			c, ok := commands[fields[2]]
+			if !ok {
+				return nil, fmt.Errorf("%d: Unknown command: %s", n, fields[2])
+			}
+			if !c.AvailableIn(m) {
+				return nil, fmt.Errorf("%d: Command %s is not available in %s mode",
+					n, c.Name, m)
+			}
+			b.Command = strings.Join(fields[2:], " ")
+		}
+		bindings = append(bindings, b)
+	}
+	return bindings, scanner.Err()
+}
+
+// Add or replace bindings
+func (km *Keymap) Apply(bindings []Binding) {
+	for _, b := range bindings {
+		km.Bind(b)
+	}
+}
+
+func (km *Keymap) Bind(b Binding) {
+	for i, kb := range km.Bindings {
+		if kb.Mode == b.Mode && kb.Key == b.Key {
+			if b.Command == "" {
+				km.Bindings = append(km.Bindings[:i], km.Bindings[i+1:]...)
+			} else {
+				km.Bindings[i].Command = b.Command
+			}
+			return
+		}
+	}
+	if b.Command != "" {
+		km.Bindings = append(km.Bindings, b)
+	}
+}
+
+func (km *Keymap) Lookup(m mode, k Key) (string, bool) {
+	for _, b := range km.Bindings {
+		if b.Mode == m && b.Key == k {
+			return b.Command, true
+		}
+	}
+	return "", false
+}
+
+type KeyHelp struct {
+	Keys []Key
+	Desc string
+}
+
+// Describe bindings of the mode grouped by command
+func (km *Keymap) Help(m mode) []KeyHelp {
+	var help []KeyHelp
+	index := make(map[string]int)
+	for _, b := range km.Bindings {
+		if b.Mode != m {
+			continue
+		}
+		if i, ok := index[b.Command]; ok {
+			help[i].Keys = append(help[i].Keys, b.Key)
+			continue
+		}
+		fields := strings.Fields(b.Command)
+		desc := commands[fields[0]].Desc
+		if len(fields) > 1 {
+			desc = fmt.Sprintf("%s (%s)", desc, strings.Join(fields[1:], " "))
+		}
+		index[b.Command] = len(help)
+		help = append(help, KeyHelp{Keys: []Key{b.Key}, Desc: desc})
+	}
+	return help
+}
+
+func (kh KeyHelp) KeysString() string {
+	s := make([]string, len(kh.Keys))
+	for i, k := range kh.Keys {
+		s[i] = k.String()
+	}
+	return strings.Join(s, ",")
+}
+
+// Use keys bound to commands. Return false if key is not bound
+func (tb *TaskBox) HandleKey(ev termbox.Event) bool {
+	cmd, ok := keymap.Lookup(tb.mode, KeyOf(ev))
+	if !ok {
+		return false
+	}
+	tb.showError(tb.ExecLine(cmd))
+	return true
+}
+
+// termbox reports Alt only in InputAlt mode
+func (km *Keymap) InputMode() termbox.InputMode {
+	for _, b := range km.Bindings {
+		if b.Key.Mod&termbox.ModAlt != 0 {
+			return termbox.InputAlt
+		}
+	}
+	return termbox.InputEsc
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"github.com/MakeNowJust/heredoc"
+	"github.com/nsf/termbox-go"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestDefaultKeymap(t *testing.T) {
+	km := DefaultKeymap()
+	cmd, ok := km.Lookup(modeTask, Key{Key: termbox.KeySpace})
+	assert.True(t, ok)
+	assert.Equal(t, "toggle", cmd)
+	cmd, ok = km.Lookup(modeArchive, Key{Key: termbox.KeyEsc})
+	assert.True(t, ok)
+	assert.Equal(t, "task-mode", cmd)
+	_, ok = km.Lookup(modeEdit, Key{Ch: 'q'})
+	assert.False(t, ok)
+}
+
+func TestParseKeymapErrors(t *testing.T) {
+	var pairs = []struct {
+		s   string
+		err string
+	}{
+		{"task x", "1: Expected \"mode key command\""},
+		{"foo x toggle", "1: Unknown mode: foo"},
+		{"task Foo toggle", "1: Unknown key: Foo"},
+		{"task x foo", "1: Unknown command: foo"},
+		{"edit Ctrl+t toggle", "1: Command toggle is not available in Edit mode"},
+		{"edit x stop-edit", "1: Can not bind x in Edit mode"},
+		{"# foo\ntask x toggle\n\ntask x delete",
+			"4: x in Task mode is already bound on line 2"},
+	}
+	for _, p := range pairs {
+		_, err := ParseKeymap(strings.NewReader(p.s))
+		assert.EqualError(t, err, p.err)
+	}
+}
+
+func TestLoadKeymap(t *testing.T) {
+	file, _ := ioutil.TempFile("", "keys")
+	defer os.Remove(file.Name())
+	ioutil.WriteFile(file.Name(), []byte(heredoc.Doc(`
+		# My keys
+		task  x       toggle
+		task  Space   -
+		task  F       filter Closed
+		edit  Ctrl+s  quit
+	`)), 0644)
+
+	km, err := LoadKeymap(file.Name())
+	assert.Nil(t, err)
+	cmd, _ := km.Lookup(modeTask, Key{Ch: 'x'})
+	assert.Equal(t, "toggle", cmd)
+	_, ok := km.Lookup(modeTask, Key{Key: termbox.KeySpace})
+	assert.False(t, ok)
+	cmd, _ = km.Lookup(modeTask, Key{Ch: 'F'})
+	assert.Equal(t, "filter Closed", cmd)
+	cmd, _ = km.Lookup(modeEdit, Key{Key: termbox.KeyCtrlS})
+	assert.Equal(t, "quit", cmd)
+
+	_, err = LoadKeymap(file.Name() + ".missing")
+	assert.Nil(t, err)
+
+	ioutil.WriteFile(file.Name(), []byte("task x foo\n"), 0644)
+	_, err = LoadKeymap(file.Name())
+	assert.EqualError(t, err, file.Name()+":1: Unknown command: foo")
+}
+
+func TestKeymapHelp(t *testing.T) {
+	km := DefaultKeymap()
+	km.Apply([]Binding{
+		{Mode: modeTask, Key: Key{Ch: 'F'}, Command: "filter Closed"},
+		{Mode: modeTask, Key: Key{Ch: 'x'}, Command: "toggle"},
+	})
+	help := km.Help(modeTask)
+	assert.Equal(t, "k,Up", help[0].KeysString())
+	assert.Equal(t, "cursor up", help[0].Desc)
+
+	var toggle, filter KeyHelp
+	for _, kh := range help {
+		switch kh.Desc {
+		case "toggle status":
+			toggle = kh
+		case "change filter [All|Open|Closed|Actionable|tag:TAG] (Closed)":
+			filter = kh
+		}
+	}
+	assert.Equal(t, "Space,x", toggle.KeysString())
+	assert.Equal(t, "F", filter.KeysString())
+}
+
+func TestHandleKey(t *testing.T) {
+	tb := TaskBoxFixture(3)
+	assert.True(t, tb.HandleKey(termbox.Event{Ch: 'j'}))
+	assert.True(t, tb.HandleKey(termbox.Event{Key: termbox.KeyArrowRight}))
+	assert.False(t, tb.HandleKey(termbox.Event{Ch: '@'}))
+	assert.Equal(t, tb.String(), heredoc.Doc(`
+		  foo
+		  baz
+		> bar
+	`))
+}
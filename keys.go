@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"github.com/nsf/termbox-go"
+	"strings"
+	"unicode/utf8"
+)
+
+// Key is a single key press or chord (e.g. Ctrl+L, Alt+x)
+type Key struct {
+	Key termbox.Key
+	Ch  rune
+	Mod termbox.Modifier
+}
+
+var keyNames = []struct {
+	name string
+	key  termbox.Key
+}{
+	{"Enter", termbox.KeyEnter},
+	{"Esc", termbox.KeyEsc},
+	{"Tab", termbox.KeyTab},
+	{"Space", termbox.KeySpace},
+	{"Backspace", termbox.KeyBackspace2},
+	{"Ins", termbox.KeyInsert},
+	{"Del", termbox.KeyDelete},
+	{"Home", termbox.KeyHome},
+	{"End", termbox.KeyEnd},
+	{"PgUp", termbox.KeyPgup},
+	{"PgDn", termbox.KeyPgdn},
+	{"Up", termbox.KeyArrowUp},
+	{"Down", termbox.KeyArrowDown},
+	{"Left", termbox.KeyArrowLeft},
+	{"Right", termbox.KeyArrowRight},
+	{"F1", termbox.KeyF1},
+	{"F2", termbox.KeyF2},
+	{"F3", termbox.KeyF3},
+	{"F4", termbox.KeyF4},
+	{"F5", termbox.KeyF5},
+	{"F6", termbox.KeyF6},
+	{"F7", termbox.KeyF7},
+	{"F8", termbox.KeyF8},
+	{"F9", termbox.KeyF9},
+	{"F10", termbox.KeyF10},
+	{"F11", termbox.KeyF11},
+	{"F12", termbox.KeyF12},
+	// Aliases. Only first name is used to print key
+	{"Return", termbox.KeyEnter},
+	{"Escape", termbox.KeyEsc},
+	{"Insert", termbox.KeyInsert},
+	{"Delete", termbox.KeyDelete},
+	{"PageUp", termbox.KeyPgup},
+	{"PageDown", termbox.KeyPgdn},
+}
+
+func KeyOf(ev termbox.Event) Key {
+	mod := ev.Mod & termbox.ModAlt
+	if ev.Ch != 0 {
+		return Key{Ch: ev.Ch, Mod: mod}
+	}
+	if ev.Key == termbox.KeyBackspace {
+		// Ctrl+H and Backspace are the same for terminal
+		return Key{Key: termbox.KeyBackspace2, Mod: mod}
+	}
+	return Key{Key: ev.Key, Mod: mod}
+}
+
+func (k Key) Event() termbox.Event {
+	return termbox.Event{Type: termbox.EventKey, Key: k.Key, Ch: k.Ch, Mod: k.Mod}
+}
+
+// Printable key without modifiers
+func (k Key) IsChar() bool {
+	return k.Ch != 0 && k.Mod == 0
+}
+
+func (k Key) String() string {
+	var prefix string
+	if k.Mod&termbox.ModAlt != 0 {
+		prefix = "Alt+"
+	}
+	if k.Ch != 0 {
+		return prefix + string(k.Ch)
+	}
+	for _, kn := range keyNames {
+		if kn.key == k.Key {
+			return prefix + kn.name
+		}
+	}
+	if k.Key >= termbox.KeyCtrlA && k.Key <= termbox.KeyCtrlZ {
+		return fmt.Sprintf("%sCtrl+%c", prefix, 'a'+rune(k.Key-termbox.KeyCtrlA))
+	}
+	return fmt.Sprintf("%s<%#x>", prefix, int(k.Key))
+}
+
+/*
+Parse key e.g.
+
+	j
+	Enter
+	Ctrl+l
+	Alt+Left
+*/
+func ParseKey(s string) (Key, error) {
+	var k Key
+	spec := s
+	if len(spec) > 4 && strings.EqualFold(spec[:4], "Alt+") {
+		k.Mod = termbox.ModAlt
+		spec = spec[4:]
+	}
+	if utf8.RuneCountInString(spec) == 1 {
+		k.Ch, _ = utf8.DecodeRuneInString(spec)
+		if k.Ch == ' ' {
+			k.Ch = 0
+			k.Key = termbox.KeySpace
+		}
+		return k, nil
+	}
+	for _, kn := range keyNames {
+		if strings.EqualFold(kn.name, spec) {
+			k.Key = kn.key
+			return k, nil
+		}
+	}
+	if len(spec) == 6 && strings.EqualFold(spec[:5], "Ctrl+") {
+		c := spec[5] | 0x20 // lower case
+		if c >= 'a' && c <= 'z' {
+			k.Key = termbox.KeyCtrlA + termbox.Key(c-'a')
+			if k.Key == termbox.KeyBackspace {
+				k.Key = termbox.KeyBackspace2
+			}
+			return k, nil
+		}
+	}
+	return k, fmt.Errorf("Unknown key: %s", s)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"github.com/nsf/termbox-go"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseKey(t *testing.T) {
+	var pairs = []struct {
+		s string
+		k Key
+	}{
+		{"j", Key{Ch: 'j'}},
+		{"J", Key{Ch: 'J'}},
+		{"?", Key{Ch: '?'}},
+		{"ф", Key{Ch: 'ф'}},
+		{"Enter", Key{Key: termbox.KeyEnter}},
+		{"enter", Key{Key: termbox.KeyEnter}},
+		{"PageDown", Key{Key: termbox.KeyPgdn}},
+		{"Space", Key{Key: termbox.KeySpace}},
+		{"Ctrl+l", Key{Key: termbox.KeyCtrlL}},
+		{"Ctrl+L", Key{Key: termbox.KeyCtrlL}},
+		{"Ctrl+h", Key{Key: termbox.KeyBackspace2}},
+		{"Alt+x", Key{Ch: 'x', Mod: termbox.ModAlt}},
+		{"Alt+Left", Key{Key: termbox.KeyArrowLeft, Mod: termbox.ModAlt}},
+	}
+	for _, p := range pairs {
+		k, err := ParseKey(p.s)
+		assert.Nil(t, err)
+		assert.Equal(t, p.k, k, p.s)
+	}
+
+	for _, s := range []string{"", "Foo", "Ctrl+1", "Ctrl+", "Alt+"} {
+		_, err := ParseKey(s)
+		assert.EqualError(t, err, "Unknown key: "+s)
+	}
+}
+
+func TestKeyString(t *testing.T) {
+	for _, s := range []string{
+		"j", "Enter", "PgDn", "Ctrl+l", "Alt+x", "Alt+Left", "Backspace",
+	} {
+		k, err := ParseKey(s)
+		assert.Nil(t, err)
+		assert.Equal(t, s, k.String())
+	}
+	k, _ := ParseKey("Delete")
+	assert.Equal(t, "Del", k.String())
+}
+
+func TestKeyOf(t *testing.T) {
+	assert.Equal(t, Key{Ch: 'j'}, KeyOf(termbox.Event{Ch: 'j'}))
+	assert.Equal(t, Key{Key: termbox.KeyBackspace2},
+		KeyOf(termbox.Event{Key: termbox.KeyBackspace}))
+	assert.Equal(t, Key{Ch: 'x', Mod: termbox.ModAlt},
+		KeyOf(termbox.Event{Ch: 'x', Mod: termbox.ModAlt | termbox.ModMotion}))
+	k := Key{Key: termbox.KeyCtrlL}
+	assert.Equal(t, k, KeyOf(k.Event()))
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"fmt"
+	"github.com/nsf/termbox-go"
+	"strconv"
+	"strings"
+)
+
+/*
+Task mode keys go through key sequence parser before key bindings:
+
+	5j     count repeats command bound to the key
+	gg G   first and last line. 5G or 5gg goes to line 5
+	ma 'a  set mark a on line and jump to it
+	.      repeat last command which changed lines
+	Qa @a  record and play macro, see macro.go
+
+Keys bound in keymap are not parsed so user bindings win.
+*/
+type keySequence struct {
+	count       int
+	prefix      rune   // g, m or ' waiting for the next key
+	repeat      string // last command line which changed lines
+	repeatCount int
+}
+
+func (k keySequence) String() string {
+	var s string
+	if k.count > 0 {
+		s = strconv.Itoa(k.count)
+	}
+	if k.prefix != 0 {
+		s += string(k.prefix)
+	}
+	return s
+}
+
+func (tb *TaskBox) HandleKeySequence(ev termbox.Event) {
+	k := &tb.keys
+	count, prefix := k.count, k.prefix
+	k.count, k.prefix = 0, 0
+	_, bound := keymap.Lookup(tb.mode, KeyOf(ev))
+	ch := ev.Ch
+	if ev.Mod != 0 || ev.Key != 0 {
+		ch = 0
+	}
+	switch {
+	case prefix == 'g':
+		if ch == 'g' {
+			tb.gotoLine(count, "first")
+		}
+	case prefix == 'm' && ch != 0:
+		tb.showError(tb.Exec("set-mark", string(ch)))
+	case prefix == '\'' && ch != 0:
+		tb.showError(tb.Exec("jump-mark", string(ch)))
+	case prefix == 'Q' && ch != 0:
+		tb.showError(tb.Exec("record", string(ch)))
+	case prefix == '@' && ch == '@' && tb.macro.last != 0:
+		tb.showError(tb.Exec("play", string(tb.macro.last), strconv.Itoa(count)))
+	case prefix == '@' && ch != 0:
+		tb.showError(tb.Exec("play", string(ch), strconv.Itoa(count)))
+	case bound || ch == 0:
+		if cmd, ok := keymap.Lookup(tb.mode, KeyOf(ev)); ok {
+			tb.runCount(cmd, count)
+		}
+	case ch >= '1' && ch <= '9' || ch == '0' && count > 0:
+		k.count = count*10 + int(ch-'0')
+	case ch == 'Q' && tb.macro.recording != 0:
+		tb.showError(tb.Exec("record"))
+	case ch == 'g' || ch == 'm' || ch == '\'' || ch == 'Q' || ch == '@':
+		k.count, k.prefix = count, ch
+	case ch == 'G':
+		tb.gotoLine(count, "last")
+	case ch == '.' && k.repeat != "":
+		if count == 0 {
+			count = k.repeatCount
+		}
+		tb.runCount(k.repeat, count)
+	}
+}
+
+// Go to line number count or run command without count
+func (tb *TaskBox) gotoLine(count int, command string) {
+	if count > 0 {
+		tb.showError(tb.Exec("line", strconv.Itoa(count)))
+	} else {
+		tb.showError(tb.Exec(command))
+	}
+}
+
+// Run command line count times. Repeat stops when command fails or
+// changes mode
+func (tb *TaskBox) runCount(line string, count int) {
+	if count < 1 {
+		count = 1
+	}
+	before := strings.Join(tb.Lines, "\n")
+	for n := 0; n < count && tb.mode == modeTask; n++ {
+		if err := tb.ExecLine(line); err != nil {
+			tb.showError(err)
+			break
+		}
+	}
+	if tb.lastCommand != "undo" && tb.lastCommand != "redo" &&
+		strings.Join(tb.Lines, "\n") != before {
+		tb.keys.repeat, tb.keys.repeatCount = line, count
+	}
+}
+
+// Move cursor to view line n starting from 1
+func (tb *TaskBox) GotoLine(n int) {
+	if len(tb.view) == 0 {
+		return
+	}
+	tb.cursor = n - 1
+	if tb.cursor >= len(tb.view) {
+		tb.cursor = len(tb.view) - 1
+	}
+	if tb.cursor < 0 {
+		tb.cursor = 0
+	}
+	tb.skipHeader(1)
+	tb.scrollToCursor()
+}
+
+func validMark(name string) (rune, error) {
+	if len(name) != 1 || name[0] < 'a' || name[0] > 'z' {
+		return 0, fmt.Errorf("Mark must be a letter a-z: %s", name)
+	}
+	return rune(name[0]), nil
+}
+
+func (tb *TaskBox) SetMark(name string) error {
+	r, err := validMark(name)
+	if err != nil {
+		return err
+	}
+	index, _ := tb.SelectedLine()
+	if index < 0 {
+		return fmt.Errorf("No line to mark")
+	}
+	if tb.marks == nil {
+		tb.marks = make(map[rune]int)
+	}
+	tb.marks[r] = index
+	return nil
+}
+
+func (tb *TaskBox) JumpToMark(name string) error {
+	r, err := validMark(name)
+	if err != nil {
+		return err
+	}
+	index, ok := tb.marks[r]
+	if !ok || index >= len(tb.Lines) {
+		return fmt.Errorf("Mark %c is not set", r)
+	}
+	tb.CursorToLine(index)
+	if i, _ := tb.SelectedLine(); i != index {
+		return fmt.Errorf("Mark %c is not visible", r)
+	}
+	return nil
+}
+
+// Keep marks on the same lines. See shiftSelection
+func (tb *TaskBox) shiftMarks(i, delta int) {
+	for r, index := range tb.marks {
+		switch {
+		case delta < 0 && index == i:
+			delete(tb.marks, r)
+		case index >= i:
+			tb.marks[r] = index + delta
+		}
+	}
+}
+
+func (tb *TaskBox) swapMarks(i, j int) {
+	for r, index := range tb.marks {
+		if index == i {
+			tb.marks[r] = j
+		} else if index == j {
+			tb.marks[r] = i
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"github.com/nsf/termbox-go"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func typeKeys(tb *TaskBox, keys string) {
+	for _, ch := range keys {
+		tb.HandleTaskEvent(termbox.Event{Ch: ch})
+	}
+}
+
+func TestCountsAndMotions(t *testing.T) {
+	tb := TaskBoxFixture(10)
+	tb.h = 10
+
+	typeKeys(tb, "5j")
+	assert.Equal(t, 5, tb.cursor)
+	typeKeys(tb, "gg")
+	assert.Equal(t, 0, tb.cursor)
+	typeKeys(tb, "G")
+	assert.Equal(t, 9, tb.cursor)
+	typeKeys(tb, "3gg")
+	assert.Equal(t, 2, tb.cursor)
+	typeKeys(tb, "12")
+	assert.Equal(t, "12", tb.keys.String())
+	typeKeys(tb, "G")
+	assert.Equal(t, 9, tb.cursor)
+	assert.Equal(t, "", tb.keys.String())
+
+	assert.Nil(t, tb.ExecLine("4"))
+	assert.Equal(t, 3, tb.cursor)
+
+	// Count for key with modifier
+	tb.HandleTaskEvent(termbox.Event{Ch: '2'})
+	tb.HandleTaskEvent(termbox.Event{Key: termbox.KeyArrowDown})
+	assert.Equal(t, 5, tb.cursor)
+}
+
+func TestCountAndRepeat(t *testing.T) {
+	tb := TaskBoxFixture(10)
+	tb.h = 10
+
+	typeKeys(tb, "3d")
+	assert.Equal(t, 7, len(tb.Lines))
+	assert.Equal(t, "qux", tb.Lines[0])
+	typeKeys(tb, "j.")
+	assert.Equal(t, []string{"qux", "garply", "waldo", "fred"}, tb.Lines)
+	typeKeys(tb, "1.")
+	assert.Equal(t, []string{"qux", "waldo", "fred"}, tb.Lines)
+	assert.Equal(t, "delete", tb.keys.repeat)
+	assert.Equal(t, 1, tb.keys.repeatCount)
+
+	// Motions are not repeated
+	typeKeys(tb, "k.")
+	assert.Equal(t, []string{"waldo", "fred"}, tb.Lines)
+}
+
+func TestJumpMarks(t *testing.T) {
+	tb := TaskBoxFixture(10)
+	tb.h = 10
+
+	typeKeys(tb, "3jma")
+	typeKeys(tb, "gg'a")
+	assert.Equal(t, 3, tb.cursor)
+
+	// Mark follows line
+	typeKeys(tb, "ggd'a")
+	assert.Equal(t, 2, tb.cursor)
+	_, s := tb.SelectedLine()
+	assert.Equal(t, "qux", s)
+
+	typeKeys(tb, "'b")
+	assert.Equal(t, "Mark b is not set", tb.message)
+	typeKeys(tb, "m1")
+	assert.Equal(t, "Mark must be a letter a-z: 1", tb.message)
+
+	typeKeys(tb, "d'a")
+	assert.Equal(t, "Mark a is not set", tb.message)
+}
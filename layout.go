@@ -0,0 +1,63 @@
+package main
+
+// Smaller terminals lose margins around task list
+const (
+	minWidth  = 20
+	minHeight = 3
+)
+
+/*
+Compute task list geometry for terminal size and return notes to
+show under the list. Calendar grid and notes pane are dropped when
+they leave too few rows for tasks, grid is also dropped when days
+do not fit in width
+*/
+func (tb *TaskBox) layout(w, h int) []string {
+	tb.x, tb.y = 1, 1
+	tb.w = w - 2 // minus margins
+	tb.h = h - 4 // minus status and margins
+	if tb.w < minWidth {
+		tb.x, tb.w = 0, w
+	}
+	if tb.h < minHeight {
+		tb.y, tb.h = 0, h-1
+	}
+	if tb.w < 1 {
+		tb.w = 1
+	}
+	if tb.h < 1 {
+		tb.h = 1
+	}
+	if tb.mode == modeCalendar && tb.h >= calendarHeight+minHeight &&
+		tb.w >= 7*calendarCell {
+		tb.y += calendarHeight
+		tb.h -= calendarHeight
+	}
+	notes := tb.CursorNotes()
+	n := tb.h/2 - 1
+	if n > notesPaneSize {
+		n = notesPaneSize
+	}
+	if n < 0 {
+		n = 0
+	}
+	if len(notes) > n {
+		notes = notes[:n]
+	}
+	if len(notes) > 0 {
+		tb.h -= len(notes) + 1
+	}
+	return notes
+}
+
+// Recompute geometry after terminal resize keeping cursor visible.
+// Editor is attached again at new position and width
+func (tb *TaskBox) Resize(w, h int) {
+	tb.layout(w, h)
+	tb.scrollToCursor()
+	if tb.editor != nil {
+		x, y := tb.editor.GetCursor()
+		tb.newEditor(tb.editor.Text())
+		tb.editor.SetCursor(x, y)
+	}
+}
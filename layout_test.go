@@ -0,0 +1,52 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestLayout(t *testing.T) {
+	tb := &TaskBox{Lines: []string{"- [ ] foo", "  note", "- [ ] bar"}}
+	tb.calculate()
+
+	notes := tb.layout(80, 24)
+	assert.Equal(t, []int{1, 1, 78, 18}, []int{tb.x, tb.y, tb.w, tb.h})
+	assert.Equal(t, []string{"note"}, notes)
+
+	// No margins and notes
+	notes = tb.layout(15, 4)
+	assert.Equal(t, []int{0, 0, 15, 3}, []int{tb.x, tb.y, tb.w, tb.h})
+	assert.Empty(t, notes)
+
+	tb.layout(1, 1)
+	assert.Equal(t, []int{0, 0, 1, 1}, []int{tb.x, tb.y, tb.w, tb.h})
+
+	tb.mode = modeCalendar
+	tb.layout(80, 24)
+	assert.Equal(t, 1+calendarHeight, tb.y)
+	tb.layout(80, 10)
+	assert.Equal(t, 1, tb.y)
+	tb.layout(20, 24)
+	assert.Equal(t, 1, tb.y)
+}
+
+func TestResize(t *testing.T) {
+	tb := &TaskBox{Lines: LinesFixture}
+	tb.calculate()
+	tb.Resize(80, 24)
+	tb.cursor = 15
+	tb.scrollToCursor()
+	assert.Equal(t, 0, tb.scroll)
+
+	tb.Resize(80, 10)
+	assert.Equal(t, 6, tb.h)
+	assert.Equal(t, 10, tb.scroll)
+
+	tb.mode = modeEdit
+	tb.AttachEditor()
+	tb.editor.SetCursor(2, 0)
+	tb.Resize(40, 12)
+	assert.Equal(t, "boop", tb.editor.Text())
+	x, _ := tb.editor.GetCursor()
+	assert.Equal(t, 2, x)
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"bufio"
+	"fmt"
 	"os"
 	"strings"
 )
@@ -25,6 +26,8 @@ func (tb *TaskBox) InsertLine(i int, line string) {
 	tb.Lines = append(tb.Lines, "")
 	copy(tb.Lines[i+1:], tb.Lines[i:])
 	tb.Lines[i] = line
+	tb.shiftSelection(i, 1)
+	tb.shiftMarks(i, 1)
 }
 
 func (tb *TaskBox) UpdateLine(i int, newL string) {
@@ -40,23 +43,36 @@ func (tb *TaskBox) DeleteLine(i int) string {
 	copy(tb.Lines[i:], tb.Lines[i+1:])
 	tb.Lines[len(tb.Lines)-1] = ""
 	tb.Lines = tb.Lines[:len(tb.Lines)-1]
+	tb.shiftSelection(i, -1)
+	tb.shiftMarks(i, -1)
 	return line
 }
 
 func (tb *TaskBox) SwapLines(i, j int) {
 	tb.Lines[i], tb.Lines[j] = tb.Lines[j], tb.Lines[i]
+	tb.swapSelection(i, j)
+	tb.swapMarks(i, j)
 }
 
 // Split line and copy everything on right to new line below
 // Return new line index
 func (tb *TaskBox) SplitLine(i, pos int) int {
+	id := lineID(tb.Lines[i])
 	runes := []rune(tb.Lines[i])
 	right := string(runes[pos:])
 	tb.UpdateLine(i, string(runes[0:pos]))
 	if lineTypeOf(tb.Lines[i]) == lineTask {
 		right = tb.TaskFilterPrefix() + right
+		// Id stays with the left part
+		if id != "" {
+			tb.UpdateLine(i, setLineID(tb.Lines[i], id))
+			if lineID(right) == id {
+				right = setLineID(right, "")
+			}
+		}
 	}
-	i++
+	// Notes stay with the left part
+	i = tb.blockEnd(i) + 1
 	tb.InsertLine(i, right)
 	return i
 }
@@ -84,16 +100,19 @@ will be transformed to
 	<!-- baz -->
 
 */
-func (tb *TaskBox) Load(path string) {
+func (tb *TaskBox) Load(path string) error {
 	tb.path = path
 	tb.Lines = make([]string, 0)
+	tb.ClearSelection()
 
 	f, err := os.Open(path)
 	if os.IsNotExist(err) {
 		// It's ok, Will create file
-		return
+		return nil
+	}
+	if err != nil {
+		return err
 	}
-	check(err)
 	defer f.Close()
 
 	hasUndo := (tb.undo != nil)
@@ -116,12 +135,15 @@ func (tb *TaskBox) Load(path string) {
 		}
 		tb.AppendLine(s)
 	}
-	check(scanner.Err())
+	if err := scanner.Err(); err != nil {
+		return err
+	}
 	tb.calculate()
 	tb.modified = false
 	if hasUndo {
 		tb.undo = NewUndo(tb) // New Clear Undo
 	}
+	return nil
 }
 
 /*
@@ -143,13 +165,20 @@ will be transformed to
 	-->
 */
 
-func (tb *TaskBox) Save(path string) {
+func (tb *TaskBox) saveLines(path string) error {
 	var comments []string
-	var err error
 
-	tb.path = path
+	if isDir(path) {
+		if tb.LineSources() == nil {
+			return fmt.Errorf("%s is a directory", path)
+		}
+		return tb.SaveWorkspace(path)
+	}
+
 	f, err := os.Create(path)
-	check(err)
+	if err != nil {
+		return err
+	}
 	defer f.Close()
 
 	w := bufio.NewWriter(f)
@@ -170,6 +199,19 @@ func (tb *TaskBox) Save(path string) {
 		}
 		w.WriteString("-->\n")
 	}
-	w.Flush()
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	tb.path = path
 	tb.modified = false
+	return nil
+}
+
+// Save lines and history events of file. See history.go
+func (tb *TaskBox) Save(path string) error {
+	from := tb.path
+	if err := tb.saveLines(path); err != nil {
+		return err
+	}
+	return tb.saveEvents(from, path)
 }
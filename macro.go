@@ -0,0 +1,210 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+/*
+Macro is a list of steps replayed through the same event handlers as
+typed keys. Step is a key name (see ParseKey) or command line starting
+with ":" run from the command prompt. Keys typed in modal prompts are
+not recorded, command with prompt is recorded with the answer as its
+arguments (see recordSteps). Viewers like help are not recorded.
+In task mode
+
+	Qa    starts recording to register a, Q stops it
+	@a    plays macro a, 3@a plays it three times
+	@@    plays the last played macro
+
+Macros are saved to config file with save-macros as lines of space
+separated steps:
+
+	macro-a j Space ":archive closed"
+*/
+
+const macroPrefix = "macro-"
+
+type macroState struct {
+	recording rune
+	steps     []string
+	playing   bool
+	last      rune
+}
+
+func validRegister(name string) (rune, error) {
+	if len(name) != 1 || name[0] < 'a' || name[0] > 'z' {
+		return 0, fmt.Errorf("Macro register must be a letter a-z: %s", name)
+	}
+	return rune(name[0]), nil
+}
+
+// Record key event while macro is being recorded
+func (tb *TaskBox) recordKey(k Key) {
+	if tb.macro.recording != 0 && !tb.macro.playing {
+		tb.macro.steps = append(tb.macro.steps, k.String())
+	}
+}
+
+// Replace key which opened command prompt with command line
+func (tb *TaskBox) recordCommand(line string, ok bool) {
+	if ok {
+		tb.recordSteps(line)
+	} else {
+		tb.recordSteps()
+	}
+}
+
+// Replace key or command line which opened modal prompt with command
+// lines doing the same without prompt. Without lines it is dropped
+func (tb *TaskBox) recordSteps(lines ...string) {
+	m := &tb.macro
+	if m.recording == 0 || m.playing || len(m.steps) == 0 {
+		return
+	}
+	m.steps = m.steps[:len(m.steps)-1]
+	for _, line := range lines {
+		m.steps = append(m.steps, ":"+line)
+	}
+}
+
+// Start recording to register or stop recording without register
+func (tb *TaskBox) RecordMacro(args []string) error {
+	m := &tb.macro
+	switch {
+	case len(args) == 0 && m.recording != 0:
+		// Drop key or command which stopped recording
+		steps := m.steps
+		if len(steps) > 0 {
+			steps = steps[:len(steps)-1]
+		}
+		if tb.macros == nil {
+			tb.macros = make(map[rune][]string)
+		}
+		tb.macros[m.recording] = steps
+		tb.message = fmt.Sprintf("Macro %c recorded", m.recording)
+		m.recording, m.steps = 0, nil
+		return nil
+	case len(args) == 0:
+		return fmt.Errorf("Expected macro register")
+	case len(args) > 1:
+		return fmt.Errorf("Too many arguments")
+	case m.recording != 0:
+		return fmt.Errorf("Already recording macro %c", m.recording)
+	}
+	r, err := validRegister(args[0])
+	if err != nil {
+		return err
+	}
+	m.recording, m.steps = r, nil
+	return nil
+}
+
+func (tb *TaskBox) PlayMacro(name string, count int) error {
+	r, err := validRegister(name)
+	if err != nil {
+		return err
+	}
+	steps, ok := tb.macros[r]
+	if !ok {
+		return fmt.Errorf("Macro %c is empty", r)
+	}
+	if tb.macro.playing {
+		return fmt.Errorf("Macro can not play macros")
+	}
+	tb.macro.playing = true
+	defer func() { tb.macro.playing = false }()
+	tb.macro.last = r
+	for n := 0; n < count && tb.mode != modeExit; n++ {
+		for _, step := range steps {
+			if len(step) > 1 && step[0] == ':' {
+				if err := tb.ExecLine(step[1:]); err != nil {
+					return err
+				}
+				continue
+			}
+			k, err := ParseKey(step)
+			if err != nil {
+				return err
+			}
+			tb.HandleEvent(k.Event())
+			tb.calculate()
+		}
+	}
+	return nil
+}
+
+func encodeMacro(steps []string) string {
+	var b strings.Builder
+	w := csv.NewWriter(&b)
+	w.Comma = ' '
+	w.Write(steps)
+	w.Flush()
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+func decodeMacro(s string) ([]string, error) {
+	r := csv.NewReader(strings.NewReader(s))
+	r.Comma = ' '
+	return r.Read()
+}
+
+// Macros from "macro-a steps" config values
+func LoadMacros(config map[string]string) (map[rune][]string, error) {
+	macros := make(map[rune][]string)
+	for name, value := range config {
+		if !strings.HasPrefix(name, macroPrefix) {
+			continue
+		}
+		r, err := validRegister(strings.TrimPrefix(name, macroPrefix))
+		if err != nil {
+			return nil, err
+		}
+		steps, err := decodeMacro(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+		for _, step := range steps {
+			if _, err := ParseKey(step); err != nil &&
+				!strings.HasPrefix(step, ":") {
+				return nil, fmt.Errorf("%s: %v", name, err)
+			}
+		}
+		macros[r] = steps
+	}
+	return macros, nil
+}
+
+// Replace macros in config file keeping other lines
+func SaveMacros(path string, macros map[rune][]string) error {
+	var lines []string
+	data, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for _, s := range strings.Split(string(data), "\n") {
+		if s != "" && !strings.HasPrefix(strings.TrimSpace(s), macroPrefix) {
+			lines = append(lines, s)
+		}
+	}
+	var names []string
+	for r, steps := range macros {
+		if len(steps) > 0 {
+			names = append(names, string(r))
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		lines = append(lines, macroPrefix+name+" "+
+			encodeMacro(macros[rune(name[0])]))
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0644)
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"github.com/nsf/termbox-go"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func sendKeys(tb *TaskBox, keys ...string) {
+	for _, name := range keys {
+		k, err := ParseKey(name)
+		check(err)
+		tb.HandleEvent(k.Event())
+		tb.calculate()
+	}
+}
+
+func TestMacro(t *testing.T) {
+	tb := &TaskBox{Lines: []string{
+		"- [ ] foo", "- [ ] bar", "- [ ] baz", "- [ ] qux",
+	}}
+	tb.h = 5
+	tb.calculate()
+
+	sendKeys(tb, "Q", "a", "Space", "j", "Q")
+	assert.Equal(t, []string{"Space", "j"}, tb.macros['a'])
+	assert.Equal(t, "Macro a recorded", tb.message)
+	assert.Equal(t, rune(0), tb.macro.recording)
+
+	sendKeys(tb, "2", "@", "a")
+	assert.Equal(t, []string{
+		"- [x] foo", "- [x] bar", "- [x] baz", "- [ ] qux",
+	}, tb.Lines)
+	sendKeys(tb, "@", "@")
+	assert.Equal(t, "- [x] qux", tb.Lines[3])
+
+	// Command line steps
+	tb.macros['b'] = []string{":filter Closed", "k"}
+	assert.Nil(t, tb.ExecLine("play b"))
+	assert.Equal(t, Status(StatusClosed), tb.filter)
+
+	assert.EqualError(t, tb.ExecLine("play c"), "Macro c is empty")
+	assert.EqualError(t, tb.ExecLine("record 1"),
+		"Macro register must be a letter a-z: 1")
+	tb.macros['c'] = []string{"@", "c"}
+	sendKeys(tb, "@", "c")
+	assert.Equal(t, "Macro can not play macros", tb.message)
+}
+
+func TestRecordCommand(t *testing.T) {
+	tb := &TaskBox{}
+	assert.Nil(t, tb.RecordMacro([]string{"a"}))
+	tb.recordKey(Key{Ch: 'j'})
+	tb.recordKey(Key{Ch: ':'})
+	tb.recordCommand("sort due", true)
+	tb.recordKey(Key{Ch: ':'})
+	tb.recordCommand("", false)
+	tb.recordKey(Key{Key: termbox.KeyEnter})
+	tb.recordKey(Key{Ch: 'Q'})
+	assert.Nil(t, tb.RecordMacro(nil))
+	assert.Equal(t, []string{"j", ":sort due", "Enter"}, tb.macros['a'])
+}
+
+func TestRecordPromptAnswer(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "macro")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "a.md")
+	tb := &TaskBox{Lines: []string{"- [ ] foo"}}
+	tb.calculate()
+	assert.Nil(t, tb.RecordMacro([]string{"a"}))
+	tb.recordKey(Key{Ch: 'W'})
+	assert.Nil(t, tb.Exec("save-as", path))
+	tb.recordKey(Key{Ch: 'Q'})
+	assert.Nil(t, tb.RecordMacro(nil))
+	assert.Equal(t, []string{":save-as " + path}, tb.macros['a'])
+
+	// Existing file is overwritten without prompt
+	tb.Lines = []string{"- [ ] bar"}
+	tb.path = ""
+	assert.Nil(t, tb.PlayMacro("a", 1))
+	data, _ := ioutil.ReadFile(path)
+	assert.Equal(t, "- [ ] bar\n", string(data))
+}
+
+func TestSaveMacros(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "macro")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "taskbox", "config")
+	os.MkdirAll(filepath.Dir(path), 0755)
+	ioutil.WriteFile(path, []byte("theme light\nmacro-z j\n"), 0644)
+
+	macros := map[rune][]string{
+		'a': {"j", "Space", ":archive closed", "\""},
+		'b': {},
+	}
+	assert.Nil(t, SaveMacros(path, macros))
+	data, _ := ioutil.ReadFile(path)
+	assert.Equal(t, "theme light\nmacro-a j Space \":archive closed\" \"\"\"\"\n",
+		string(data))
+
+	config, err := LoadConfig(path)
+	assert.Nil(t, err)
+	loaded, err := LoadMacros(config)
+	assert.Nil(t, err)
+	assert.Equal(t, map[rune][]string{'a': macros['a']}, loaded)
+
+	_, err = LoadMacros(map[string]string{"macro-a": "j Foo"})
+	assert.EqualError(t, err, "macro-a: Unknown key: Foo")
+	_, err = LoadMacros(map[string]string{"macro-1": "j"})
+	assert.EqualError(t, err, "Macro register must be a letter a-z: 1")
+}
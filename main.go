@@ -7,42 +7,17 @@ import (
 	"github.com/smetana/editbox-go"
 	"os"
 	"strings"
+	"sync/atomic"
 	"time"
 )
 
 var autosaveInterval time.Duration
 
-func help() {
-	termbox.Clear(0, 0)
-	var shortcuts = []struct{ key, desc string }{
-		{"k,Up", "cursor up"},
-		{"j,Down", "cursor down"},
-		{"Enter", "edit"},
-		{"Esc", "stop edit"},
-		{"Tab", "insert \"- [ ]\" (in Edit mode)"},
-		{"i,Ins", "insert line"},
-		{"d,Del", "delete line"},
-		{"Space", "toggle status"},
-		{"h,Left", "move line up"},
-		{"l,Right", "move line down"},
-		{"Ctrl+l", "move line to the bottom"},
-		{"c", "insert copy of the line"},
-		{"z", "archive line (unarchive line)"},
-		{"f", "change filter"},
-		{"Ctrl+f", "go to archive"},
-		{"u", "undo"},
-		{"r", "redo"},
-		{"?", "help"},
-		{"s,w", "save"},
-		{"q", "quit"},
-	}
-	for i, sc := range shortcuts {
-		editbox.Label(1, i+1, 8, 0|termbox.AttrBold, 0,
-			fmt.Sprintf("%8s", sc.key))
-		editbox.Label(11, i+1, 0, 0, 0, sc.desc)
-	}
-	termbox.Flush()
-	termbox.PollEvent()
+// Set by autosave goroutine, files are saved by main loop
+var autosaveDue int32
+
+func help(m mode) {
+	pager("Help", helpLines(m, flag.CommandLine))
 }
 
 func check(e error) {
@@ -51,21 +26,280 @@ func check(e error) {
 	}
 }
 
+// Report error before terminal UI is started
+func exitOnError(e error) {
+	if e != nil {
+		fmt.Fprintln(os.Stderr, e)
+		os.Exit(1)
+	}
+}
+
 func confirm(msg string) (bool, termbox.Event) {
 	w, h := termbox.Size()
-	// Clear line
-	editbox.Label(1, h-1, w, 0, 0, "")
-	return editbox.Confirm(1, h-1, 0|termbox.AttrBold, 0, msg)
+	for {
+		// Clear line
+		editbox.Label(0, h-1, w, theme.Status.Fg, theme.Status.Bg, "")
+		yes, ev := editbox.Confirm(1, h-1, theme.Status.Fg|termbox.AttrBold,
+			theme.Status.Bg, msg)
+		// Background wake up is not an answer
+		if ev.Type != termbox.EventInterrupt {
+			return yes, ev
+		}
+	}
 }
 
-func (tb *TaskBox) render() {
-	termbox.Clear(0, 0)
+// Read line of text in status line. Return false on Esc
+func prompt(msg string) (string, bool) {
+	return readLine(msg, nil, nil)
+}
+
+/*
+Read line of text in status line with history (Up, Down) and
+completion (Tab). Completions are listed above status line
+*/
+func readLine(msg string, history []string,
+	complete func(string) []string) (string, bool) {
 	w, h := termbox.Size()
-	tb.w = w - 2 // minus margins
-	tb.h = h - 4 // minus status and margins
-	tb.x = 1
-	tb.y = 1
-	editbox.Text(tb.x, tb.y, 0, 0, 0, 0, tb.String())
+	editbox.Label(0, h-1, w, theme.Status.Fg, theme.Status.Bg, msg)
+	input := editbox.Input(len(msg), h-1, w-len(msg),
+		theme.Editor.Fg, theme.Editor.Bg)
+	defer termbox.HideCursor()
+	setText := func(s string) {
+		input.SetText(s)
+		input.SetCursor(len([]rune(s)), 0)
+	}
+	n := len(history)
+	for {
+		input.Render()
+		termbox.Flush()
+		ev := termbox.PollEvent()
+		switch {
+		case ev.Key == termbox.KeyEnter:
+			return input.Text(), true
+		case ev.Key == termbox.KeyEsc:
+			return "", false
+		case ev.Type == termbox.EventInterrupt:
+			// Background wake up, see mainLoop
+		case ev.Key == termbox.KeyArrowUp && n > 0:
+			n--
+			setText(history[n])
+		case ev.Key == termbox.KeyArrowDown && n < len(history):
+			n++
+			if n == len(history) {
+				setText("")
+			} else {
+				setText(history[n])
+			}
+		case ev.Key == termbox.KeyTab && complete != nil:
+			lines := complete(input.Text())
+			switch len(lines) {
+			case 0:
+			case 1:
+				s := lines[0]
+				if !strings.HasSuffix(s, string(os.PathSeparator)) {
+					s += " "
+				}
+				setText(s)
+			default:
+				setText(commonPrefix(lines))
+				var values []string
+				for _, s := range lines {
+					values = append(values, s[strings.LastIndex(s, " ")+1:])
+				}
+				editbox.Label(0, h-2, w, theme.Normal.Fg, theme.Normal.Bg,
+					strings.Join(values, "  "))
+			}
+		default:
+			input.HandleEvent(ev)
+		}
+	}
+}
+
+// Let user choose item from list. Return -1 on Esc
+func chooseItem(title string, items []string, selected int) int {
+	for {
+		termbox.Clear(theme.Normal.Fg, theme.Normal.Bg)
+		w, _ := termbox.Size()
+		editbox.Label(1, 1, w-2, theme.Heading.Fg, theme.Heading.Bg, title)
+		for i, item := range items {
+			style := theme.Normal
+			if i == selected {
+				style = theme.Cursor
+			}
+			editbox.Label(1, i+3, w-2, style.Fg, style.Bg, "  "+item)
+		}
+		termbox.Flush()
+		ev := termbox.PollEvent()
+		switch {
+		case ev.Key == termbox.KeyEnter:
+			return selected
+		case ev.Key == termbox.KeyEsc || ev.Ch == 'q':
+			return -1
+		case (ev.Key == termbox.KeyArrowDown || ev.Ch == 'j') &&
+			selected < len(items)-1:
+			selected++
+		case (ev.Key == termbox.KeyArrowUp || ev.Ch == 'k') && selected > 0:
+			selected--
+		case ev.Ch >= '1' && ev.Ch <= '9' && int(ev.Ch-'1') < len(items):
+			return int(ev.Ch - '1')
+		}
+	}
+}
+
+/*
+Show text until Esc or q. Long text is scrolled, / shows only lines
+matching search (see searchLines), Esc clears search. Lines which are
+not indented are headings
+*/
+func pager(title string, text []string) {
+	top := 0
+	lines := text
+	query := ""
+	for {
+		termbox.Clear(theme.Normal.Fg, theme.Normal.Bg)
+		w, h := termbox.Size()
+		page := h - 4
+		if page < 1 {
+			page = 1
+		}
+		editbox.Label(1, 1, w-2, theme.Heading.Fg, theme.Heading.Bg, title)
+		if len(lines) == 0 {
+			editbox.Label(1, 3, w-2, theme.Normal.Fg, theme.Normal.Bg,
+				"No matches for "+query)
+		}
+		for i := top; i < len(lines) && i < top+page; i++ {
+			style := theme.Normal
+			if lines[i] != "" && !strings.HasPrefix(lines[i], " ") {
+				style = theme.Heading
+			}
+			editbox.Label(1, i-top+3, w-2, style.Fg, style.Bg, lines[i])
+		}
+		status := " j,k,PgUp,PgDn scroll; / search; q quit"
+		if query != "" {
+			status += "; Search:" + query
+		}
+		editbox.Label(0, h-1, w, theme.Status.Fg, theme.Status.Bg, status)
+		termbox.Flush()
+		ev := termbox.PollEvent()
+		switch {
+		case ev.Ch == '/':
+			if s, ok := readLine("/", nil, nil); ok {
+				query = strings.TrimSpace(s)
+				lines = searchLines(text, query)
+				top = 0
+			}
+		case ev.Key == termbox.KeyEsc && query != "":
+			query = ""
+			lines = text
+			top = 0
+		case ev.Key == termbox.KeyEsc || ev.Key == termbox.KeyEnter ||
+			ev.Ch == 'q':
+			return
+		case ev.Key == termbox.KeyArrowDown || ev.Ch == 'j':
+			top++
+		case ev.Key == termbox.KeyArrowUp || ev.Ch == 'k':
+			top--
+		case ev.Key == termbox.KeyPgdn || ev.Key == termbox.KeySpace:
+			top += page
+		case ev.Key == termbox.KeyPgup:
+			top -= page
+		case ev.Key == termbox.KeyHome || ev.Ch == 'g':
+			top = 0
+		case ev.Key == termbox.KeyEnd || ev.Ch == 'G':
+			top = len(lines)
+		}
+		if top > len(lines)-page {
+			top = len(lines) - page
+		}
+		if top < 0 {
+			top = 0
+		}
+	}
+}
+
+func (tb *TaskBox) CommandPrompt() {
+	line, ok := readLine(":", commandHistory, tb.Complete)
+	tb.recordCommand(line, ok)
+	if ok {
+		addHistory(strings.TrimSpace(line))
+		tb.showError(tb.ExecLine(line))
+	}
+}
+
+// Ask for file path unless it is given in args
+func pathArg(msg string, args []string) (string, error) {
+	switch len(args) {
+	case 0:
+		path, ok := readLine(msg, nil, completePath)
+		if !ok {
+			return "", nil
+		}
+		return strings.TrimSpace(path), nil
+	case 1:
+		return args[0], nil
+	}
+	return "", fmt.Errorf("Too many arguments")
+}
+
+// Save to another file. Existing file is overwritten after confirmation.
+// Macro is recorded with the answers and plays without prompts
+func (tb *TaskBox) SaveAsPrompt(args []string) error {
+	path, err := pathArg("Save as: ", args)
+	if path == "" || err != nil {
+		tb.recordSteps()
+		return err
+	}
+	if _, err := os.Stat(path); err == nil && path != tb.path &&
+		!tb.macro.playing {
+		if yes, _ := confirm("Overwrite " + path); !yes {
+			tb.recordSteps()
+			return nil
+		}
+	}
+	tb.recordSteps("save-as " + path)
+	if err := tb.Save(path); err != nil {
+		return err
+	}
+	tb.message = "Saved " + path
+	return nil
+}
+
+// Open file in place of current one. Unsaved changes are saved
+// after confirmation, Esc cancels. Open file is just switched to.
+// Saving is recorded to macro as separate :save step
+func (tb *TaskBox) OpenPrompt(args []string) error {
+	path, err := pathArg("Open: ", args)
+	if path == "" || err != nil {
+		tb.recordSteps()
+		return err
+	}
+	steps := []string{"open " + path}
+	if tb.modified && tb.findBuffer(path) < 0 && !tb.macro.playing {
+		yes, ev := confirm("Save " + tb.path)
+		if ev.Key == termbox.KeyEsc {
+			tb.recordSteps()
+			return nil
+		}
+		if yes {
+			if err := tb.Save(tb.path); err != nil {
+				return err
+			}
+			steps = []string{"save", "open " + path}
+		}
+	}
+	tb.recordSteps(steps...)
+	return tb.OpenFile(path)
+}
+
+func (tb *TaskBox) render() {
+	termbox.Clear(theme.Normal.Fg, theme.Normal.Bg)
+	notes := tb.layout(termbox.Size())
+	tb.scrollToCursor()
+	if tb.mode == modeCalendar && tb.y >= calendarHeight {
+		tb.renderCalendar(tb.y - calendarHeight)
+	}
+	tb.renderLines()
+	tb.renderNotes(notes)
 
 	if tb.editor != nil {
 		tb.editor.Render()
@@ -75,12 +309,86 @@ func (tb *TaskBox) render() {
 	termbox.Flush()
 }
 
+func (tb *TaskBox) renderLines() {
+	if len(tb.view) == 0 {
+		editbox.Label(tb.x, tb.y, tb.w, theme.Normal.Fg, theme.Normal.Bg,
+			tb.emptyMessage())
+		return
+	}
+	sources := tb.LineSources()
+	y := tb.y
+	for i, index := range tb.page() {
+		var style Style
+		switch {
+		case index < 0:
+			// Agenda group header
+			style = theme.Heading
+		case tb.isBlocked(tb.Lines[index]):
+			style = theme.Blocked
+		default:
+			style = theme.LineStyle(tb.Lines[index])
+		}
+		if i == tb.CursorToPage() {
+			style = theme.Cursor
+		} else if tb.HasSelection() && tb.isSelectedAt(tb.scroll+i) {
+			style = theme.Selected
+		}
+		rows := tb.displayRows(i, index)
+		for r, row := range rows {
+			if y+r < tb.y+tb.h {
+				editbox.Label(tb.x, y+r, tb.w, style.Fg, style.Bg, row)
+			}
+		}
+		if sources != nil && index >= 0 && len(sources[index])+1 < tb.w/2 {
+			// Show file of workspace line at right
+			src := " " + sources[index]
+			editbox.Label(tb.x+tb.w-len(src), y, len(src),
+				theme.Archived.Fg, style.Bg, src)
+		}
+		y += len(rows)
+	}
+}
+
+// Detail pane with notes of task under cursor
+func (tb *TaskBox) renderNotes(notes []string) {
+	y := tb.y + tb.h + 1
+	for i, s := range notes {
+		editbox.Label(tb.x, y+i, tb.w, theme.Archived.Fg, theme.Archived.Bg,
+			"  "+s)
+	}
+}
+
 func (tb *TaskBox) renderStatusLine() {
 	w, h := termbox.Size()
 	var s strings.Builder
+	if bl := tb.BufferLine(); bl != "" {
+		fmt.Fprintf(&s, " %s |", bl)
+	}
 	fmt.Fprintf(&s, " Mode:%s", tb.mode.String())
 	if tb.mode != modeArchive {
 		fmt.Fprintf(&s, "; Filter:%s", tb.filter.String())
+		if tb.tag != "" {
+			fmt.Fprintf(&s, " #%s", tb.tag)
+		}
+	}
+	if tb.sorted {
+		fmt.Fprintf(&s, "; Sort:Priority")
+	}
+	if tb.mode == modeCalendar {
+		fmt.Fprintf(&s, "; Day:%s", tb.day.Format(DateFormat))
+	}
+	if tb.macro.recording != 0 {
+		fmt.Fprintf(&s, "; Recording:%c", tb.macro.recording)
+	}
+	if keys := tb.keys.String(); keys != "" {
+		fmt.Fprintf(&s, "; Keys:%s", keys)
+	}
+	if n := tb.SelectionSize(); n > 0 {
+		fmt.Fprintf(&s, "; Selected:%d", n)
+	}
+	if tb.timer != nil {
+		fmt.Fprintf(&s, "; Timer:%s %s",
+			formatDuration(time.Since(tb.timer.Start)), tb.timer.Description)
 	}
 	if autosaveInterval > 0 {
 		fmt.Fprintf(&s, "; Autosave:%.0fm", autosaveInterval.Minutes())
@@ -93,7 +401,34 @@ func (tb *TaskBox) renderStatusLine() {
 		}
 	}
 	fmt.Fprintf(&s, "    %d:%d", tb.undo.stateIndex, len(tb.undo.history))
-	editbox.Label(0, h-1, w, 0, 0, s.String())
+	if tb.message != "" {
+		fmt.Fprintf(&s, "    %s", tb.message)
+	}
+	editbox.Label(0, h-1, w, theme.Status.Fg, theme.Status.Bg, s.String())
+}
+
+// Dispatch event to handler of current mode. Keys are recorded to
+// macro if it is being recorded
+func (tb *TaskBox) HandleEvent(ev termbox.Event) {
+	if ev.Type == termbox.EventKey {
+		tb.recordKey(KeyOf(ev))
+	}
+	switch {
+	case ev.Type == termbox.EventResize:
+		tb.Resize(ev.Width, ev.Height)
+	case ev.Type == termbox.EventMouse:
+		tb.HandleMouse(ev)
+	case tb.mode == modeTask:
+		tb.HandleTaskEvent(ev)
+	case tb.mode == modeEdit:
+		tb.HandleEditEvent(ev)
+	case tb.mode == modeArchive:
+		tb.HandleArchiveEvent(ev)
+	case tb.mode == modeAgenda:
+		tb.HandleAgendaEvent(ev)
+	case tb.mode == modeCalendar:
+		tb.HandleCalendarEvent(ev)
+	}
 }
 
 func (tb *TaskBox) mainLoop() {
@@ -103,28 +438,31 @@ func (tb *TaskBox) mainLoop() {
 			panic(ev.Err)
 		}
 		if ev.Type == termbox.EventInterrupt {
-			tb.mode = modeExit
+			tb.wakeUp()
+			continue
 		}
+		tb.lastCommand = ""
+		tb.message = ""
 
-		switch tb.mode {
-		case modeTask:
-			tb.HandleTaskEvent(ev)
-		case modeEdit:
-			tb.HandleEditEvent(ev)
-		case modeArchive:
-			tb.HandleArchiveEvent(ev)
-		}
+		tb.HandleEvent(ev)
 
-		if !(ev.Ch == 'r' || ev.Ch == 'u') {
+		if tb.lastCommand != "undo" && tb.lastCommand != "redo" {
 			tb.undo.PutState()
 		}
 
-		if tb.mode == modeExit && tb.modified {
-			yes, ev := confirm("Save " + tb.path)
+		if tb.mode == modeExit && tb.AnyModified() {
+			msg := "Save " + tb.path
+			if len(tb.buffers) > 1 {
+				msg = "Save modified files"
+			}
+			yes, ev := confirm(msg)
 			if ev.Key == termbox.KeyEsc {
 				tb.mode = modeTask
 			} else if yes {
-				tb.Save(tb.path)
+				if err := tb.SaveAll(); err != nil {
+					tb.showError(err)
+					tb.mode = modeTask
+				}
 			}
 		}
 
@@ -133,53 +471,133 @@ func (tb *TaskBox) mainLoop() {
 	}
 }
 
-func autosave(tb *TaskBox, d time.Duration) {
+/*
+Background goroutines do not touch TaskBox or screen. They wake main
+loop up with termbox.Interrupt. Modal prompts skip the interrupt, then
+its work is done on the next one
+*/
+func (tb *TaskBox) wakeUp() {
+	saved := false
+	if atomic.SwapInt32(&autosaveDue, 0) == 1 && tb.AnyModified() {
+		tb.message = ""
+		tb.showError(tb.SaveAll())
+		saved = true
+	}
+	if saved || tb.timer != nil {
+		tb.render()
+	}
+}
+
+func autosave(d time.Duration) {
 	for {
 		<-time.After(d)
-		if tb.modified {
-			tb.Save(tb.path)
-			tb.renderStatusLine()
-			termbox.Flush()
+		atomic.StoreInt32(&autosaveDue, 1)
+		termbox.Interrupt()
+	}
+}
+
+// Keep running timer in status line up to date
+func tickTimer() {
+	for {
+		<-time.After(time.Second)
+		if atomic.LoadInt32(&timerRunning) == 1 {
+			termbox.Interrupt()
 		}
 	}
 }
 
 func main() {
 	flag.Usage = func() {
-		fmt.Println("Usage:\n  taskbox [options] filename\n\nOptions:")
+		fmt.Println("Usage:\n  taskbox [options] filename|directory...\n" +
+			"  taskbox stats filename|directory\n\nOptions:")
 		flag.PrintDefaults()
 		fmt.Println()
 	}
 	flagStatus := flag.String("status", "",
-		"Filter by task status on start (All,Open,Closed)")
+		"Filter by task status on start (All,Open,Closed,Actionable)")
 	flagAutosave := flag.Int("autosave", 0,
 		"Autosave interval in minutes (0 = Disable)")
+	flagKeys := flag.String("keys", configPath("keys"),
+		"Key bindings file")
+	flagTheme := flag.String("theme", "",
+		"Color theme ("+themeNames()+")")
+	flagConfig := flag.String("config", configPath("config"),
+		"Config file")
+	flagWorkspace := flag.Bool("workspace", false,
+		"Open directory as one list of all task lists found recursively")
 	flag.Parse()
 
 	if len(flag.Args()) == 0 {
 		flag.Usage()
 		os.Exit(1)
 	}
+	if flag.Arg(0) == "stats" && len(flag.Args()) == 2 {
+		exitOnError(PrintStats(flag.Arg(1)))
+		return
+	}
 	autosaveInterval = time.Duration(*flagAutosave) * time.Minute
 
+	configFile = *flagConfig
+	config, err := LoadConfig(configFile)
+	exitOnError(err)
+	keymap, err = LoadKeymap(*flagKeys)
+	exitOnError(err)
+	theme, err = ChooseTheme(*flagTheme, config["theme"],
+		os.Getenv("NO_COLOR") != "")
+	exitOnError(err)
+	clipboard, err = DetectClipboard(config["clipboard"])
+	exitOnError(err)
+	switch config["ids"] {
+	case "", "off":
+	case "on":
+		autoIDs = true
+	default:
+		exitOnError(fmt.Errorf("Unknown ids: %s (on,off)", config["ids"]))
+	}
+	switch config["wrap"] {
+	case "", "on":
+	case "off":
+		wordWrap = false
+	default:
+		exitOnError(fmt.Errorf("Unknown wrap: %s (on,off)", config["wrap"]))
+	}
+	switch config["mouse"] {
+	case "", "on":
+	case "off":
+		mouseEnabled = false
+	default:
+		exitOnError(fmt.Errorf("Unknown mouse: %s (on,off)", config["mouse"]))
+	}
+
 	tb := &TaskBox{filter: StatusFromString(*flagStatus)}
 	tb.undo = NewUndo(tb)
+	tb.macros, err = LoadMacros(config)
+	exitOnError(err)
 
-	filename := flag.Args()[0]
-	tb.Load(filename)
+	exitOnError(tb.OpenFiles(flag.Args(), *flagWorkspace))
 
-	err := termbox.Init()
+	err = termbox.Init()
 	check(err)
-	termbox.SetInputMode(termbox.InputEsc)
+	if mouseEnabled {
+		termbox.SetInputMode(keymap.InputMode() | termbox.InputMouse)
+	} else {
+		termbox.SetInputMode(keymap.InputMode())
+	}
+	termbox.SetOutputMode(theme.Output)
 	termbox.HideCursor()
 
 	tb.render()
 
 	if *flagAutosave > 0 {
-		go autosave(tb, autosaveInterval)
+		go autosave(autosaveInterval)
 	}
 
+	go tickTimer()
+
 	tb.mainLoop()
 
 	termbox.Close()
+	if err := tb.StopTimer(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
 }
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"github.com/nsf/termbox-go"
+	"time"
+)
+
+/*
+Mouse: click moves cursor, click on [ ] toggles task, double click
+edits, wheel moves cursor and drag moves line in task mode. Mouse is
+off with "mouse off" in config file
+*/
+
+var mouseEnabled = true
+
+const (
+	doubleClick = 400 * time.Millisecond
+	wheelLines  = 3
+)
+
+type mouseState struct {
+	lastClick time.Time
+	lastPos   int
+	dragging  bool
+}
+
+func (tb *TaskBox) HandleMouse(ev termbox.Event) {
+	switch {
+	case ev.Key == termbox.MouseLeft && ev.Mod&termbox.ModMotion == 0:
+		tb.click(ev.MouseX, ev.MouseY)
+	case ev.Key == termbox.MouseLeft:
+		tb.drag(ev.MouseY)
+	case ev.Key == termbox.MouseRelease:
+		tb.mouse.dragging = false
+	case ev.Key == termbox.MouseWheelUp && tb.mode != modeEdit:
+		for i := 0; i < wheelLines; i++ {
+			tb.CursorUp()
+		}
+	case ev.Key == termbox.MouseWheelDown && tb.mode != modeEdit:
+		for i := 0; i < wheelLines; i++ {
+			tb.CursorDown()
+		}
+	}
+}
+
+// View position of line shown at screen row y or -1
+func (tb *TaskBox) posAtY(y int) int {
+	if y < tb.y {
+		return -1
+	}
+	row := tb.y
+	for pos := tb.scroll; pos < len(tb.view) && row < tb.y+tb.h; pos++ {
+		row += tb.lineHeight(pos)
+		if y < row {
+			return pos
+		}
+	}
+	return -1
+}
+
+func (tb *TaskBox) click(x, y int) {
+	if tb.mode == modeEdit {
+		if tb.posAtY(y) == tb.cursor {
+			tb.clickEditor(x, y)
+			return
+		}
+		tb.ExitEditMode()
+		tb.calculate()
+	}
+	pos := tb.posAtY(y)
+	if pos < 0 || tb.view[pos] < 0 {
+		return
+	}
+	double := pos == tb.mouse.lastPos &&
+		time.Since(tb.mouse.lastClick) < doubleClick
+	tb.mouse.lastPos, tb.mouse.lastClick = pos, time.Now()
+	tb.ClearSelection()
+	tb.cursor = pos
+	tb.scrollToCursor()
+	tb.mouse.dragging = tb.mode == modeTask
+	switch {
+	case tb.onCheckbox(x, y):
+		if commands["toggle"].AvailableIn(tb.mode) {
+			tb.showError(tb.Exec("toggle"))
+		}
+	case double:
+		tb.mouse.dragging = false
+		if commands["edit"].AvailableIn(tb.mode) {
+			tb.showError(tb.Exec("edit"))
+		}
+	}
+}
+
+// Checkbox of task under cursor is at x,y
+func (tb *TaskBox) onCheckbox(x, y int) bool {
+	_, s := tb.SelectedLine()
+	return lineTypeOf(s) == lineTask && y == tb.CursorToY() &&
+		x >= tb.x+2 && x < tb.x+2+len(TaskPrefix)-1
+}
+
+// Move editor cursor to x,y of wrapped line
+func (tb *TaskBox) clickEditor(x, y int) {
+	text := []rune(tb.editor.Text())
+	pos := x - tb.x - 2
+	rows := tb.wrap(string(text))
+	for r := 0; r < y-tb.CursorToY() && r < len(rows); r++ {
+		pos += len([]rune(rows[r]))
+	}
+	if pos > len(text) {
+		pos = len(text)
+	}
+	if pos < 0 {
+		pos = 0
+	}
+	tb.editor.SetCursor(pos, 0)
+	tb.lastX = pos
+}
+
+// Move line under cursor to screen row y
+func (tb *TaskBox) drag(y int) {
+	pos := tb.posAtY(y)
+	if !tb.mouse.dragging || pos < 0 {
+		return
+	}
+	for tb.cursor != pos {
+		cursor := tb.cursor
+		if tb.cursor < pos {
+			tb.showError(tb.Exec("move-down"))
+		} else {
+			tb.showError(tb.Exec("move-up"))
+		}
+		if tb.cursor == cursor {
+			// Lines do not move e.g. in sorted view
+			return
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"github.com/nsf/termbox-go"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func mouseEvent(key termbox.Key, x, y int) termbox.Event {
+	return termbox.Event{Type: termbox.EventMouse, Key: key, MouseX: x, MouseY: y}
+}
+
+func TestMouse(t *testing.T) {
+	tb := &TaskBox{Lines: []string{"- [ ] foo", "- [ ] bar", "- [ ] baz"}}
+	tb.undo = NewUndo(tb)
+	tb.h = 5
+	tb.calculate()
+
+	assert.Equal(t, 2, tb.posAtY(2))
+	assert.Equal(t, -1, tb.posAtY(3))
+
+	tb.HandleMouse(mouseEvent(termbox.MouseLeft, 10, 1))
+	tb.HandleMouse(mouseEvent(termbox.MouseRelease, 10, 1))
+	_, s := tb.SelectedLine()
+	assert.Equal(t, "- [ ] bar", s)
+
+	// Checkbox
+	tb.HandleMouse(mouseEvent(termbox.MouseLeft, 4, 2))
+	tb.HandleMouse(mouseEvent(termbox.MouseRelease, 4, 2))
+	assert.Equal(t, "- [x] baz", tb.Lines[2])
+
+	// Drag
+	tb.HandleMouse(mouseEvent(termbox.MouseLeft, 10, 0))
+	ev := mouseEvent(termbox.MouseLeft, 10, 2)
+	ev.Mod = termbox.ModMotion
+	tb.HandleMouse(ev)
+	tb.HandleMouse(mouseEvent(termbox.MouseRelease, 10, 2))
+	assert.Equal(t, []string{"- [ ] bar", "- [x] baz", "- [ ] foo"}, tb.Lines)
+	assert.Equal(t, 2, tb.cursor)
+
+	tb.HandleMouse(mouseEvent(termbox.MouseWheelUp, 0, 0))
+	assert.Equal(t, 0, tb.cursor)
+
+	// Double click
+	tb.HandleMouse(mouseEvent(termbox.MouseLeft, 10, 1))
+	tb.HandleMouse(mouseEvent(termbox.MouseRelease, 10, 1))
+	tb.HandleMouse(mouseEvent(termbox.MouseLeft, 10, 1))
+	assert.Equal(t, modeEdit, tb.mode)
+	assert.Equal(t, "- [x] baz", tb.editor.Text())
+
+	tb.HandleMouse(mouseEvent(termbox.MouseLeft, 9, 1))
+	x, _ := tb.editor.GetCursor()
+	assert.Equal(t, 7, x)
+
+	// Click on other line ends editing
+	tb.HandleMouse(mouseEvent(termbox.MouseLeft, 10, 0))
+	assert.Equal(t, modeTask, tb.mode)
+	assert.Equal(t, 0, tb.cursor)
+}
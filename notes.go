@@ -0,0 +1,161 @@
+package main
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+/*
+Indented lines right below a task are its notes:
+
+	- [ ] foo
+	  note about foo
+	  one more note
+
+Notes are hidden in the list and shown in detail pane below it.
+They are moved, copied, archived and deleted along with the task.
+When notes are shown in the list they are edited as usual lines.
+*/
+
+var (
+	reNote = regexp.MustCompile(`^(\t| {2}).*\S`)
+	// Nested tasks and list items are not notes
+	reSubItem = regexp.MustCompile(`^\s+[-*] (\[.\] )?`)
+)
+
+func isNote(s string) bool {
+	return reNote.MatchString(s) && !reSubItem.MatchString(s)
+}
+
+// Max lines of detail pane
+const notesPaneSize = 5
+
+// Line text without archive comment
+func lineContent(s string) string {
+	if lineTypeOf(s) == lineComment {
+		return ParseComment(s)
+	}
+	return s
+}
+
+// Index of the last note of task at index i (i if there are no notes)
+func (tb *TaskBox) noteEnd(i int) int {
+	s := tb.Lines[i]
+	if lineTypeOf(lineContent(s)) != lineTask {
+		return i
+	}
+	archived := lineTypeOf(s) == lineComment
+	for i+1 < len(tb.Lines) {
+		next := tb.Lines[i+1]
+		if (lineTypeOf(next) == lineComment) != archived ||
+			!isNote(lineContent(next)) {
+			break
+		}
+		i++
+	}
+	return i
+}
+
+// Last line of task block. Notes shown in the list are usual lines
+func (tb *TaskBox) blockEnd(i int) int {
+	if tb.showNotes {
+		return i
+	}
+	return tb.noteEnd(i)
+}
+
+// Which lines are notes
+func (tb *TaskBox) noteLines() []bool {
+	notes := make([]bool, len(tb.Lines))
+	for i := 0; i < len(tb.Lines); i++ {
+		end := tb.noteEnd(i)
+		for ; i < end; i++ {
+			notes[i+1] = true
+		}
+	}
+	return notes
+}
+
+// Line indexes with notes of their tasks in ascending order
+func (tb *TaskBox) withNotes(indexes []int) []int {
+	indexes = append([]int(nil), indexes...)
+	sort.Ints(indexes)
+	var all []int
+	for _, i := range indexes {
+		for k := i; k <= tb.blockEnd(i); k++ {
+			if len(all) == 0 || k > all[len(all)-1] {
+				all = append(all, k)
+			}
+		}
+	}
+	return all
+}
+
+// Notes of task under cursor if they are hidden
+func (tb *TaskBox) CursorNotes() []string {
+	i, _ := tb.SelectedLine()
+	if i < 0 || tb.showNotes {
+		return nil
+	}
+	var notes []string
+	for k := i + 1; k <= tb.noteEnd(i); k++ {
+		notes = append(notes, strings.TrimSpace(lineContent(tb.Lines[k])))
+	}
+	return notes
+}
+
+func (tb *TaskBox) ToggleNotes() {
+	i, _ := tb.SelectedLine()
+	tb.showNotes = !tb.showNotes
+	tb.calculate()
+	if i >= 0 {
+		tb.cursorToBlock(i)
+	}
+}
+
+// Move cursor to line or to its task if line is hidden note
+func (tb *TaskBox) cursorToBlock(i int) {
+	for i > 0 && !tb.showNotes && tb.noteLines()[i] {
+		i--
+	}
+	tb.CursorToLine(i)
+}
+
+// Insert note below notes of task under cursor and edit it
+func (tb *TaskBox) AddNote() {
+	i, s := tb.SelectedLine()
+	if lineTypeOf(s) != lineTask {
+		return
+	}
+	end := tb.noteEnd(i)
+	tb.InsertLine(end+1, "  ")
+	tb.addedNote, tb.notesShown = end+1, tb.showNotes
+	tb.showNotes = true
+	tb.calculate()
+	tb.CursorToLine(end + 1)
+	tb.EnterEditMode()
+}
+
+// Move line by swapping it with neighbours so selection follows lines
+func (tb *TaskBox) moveLine(from, to int) {
+	for ; from < to; from++ {
+		tb.SwapLines(from, from+1)
+	}
+	for ; from > to; from-- {
+		tb.SwapLines(from, from-1)
+	}
+}
+
+// Swap task blocks starting at i < j. Lines between blocks stay
+func (tb *TaskBox) swapBlocks(i, j int) {
+	aLen := tb.blockEnd(i) - i + 1
+	bLen := tb.blockEnd(j) - j + 1
+	gap := j - i - aLen
+	for k := 0; k < bLen; k++ {
+		tb.moveLine(j+k, i+k)
+	}
+	for k := 0; k < gap; k++ {
+		tb.moveLine(i+bLen+aLen+k, i+bLen+k)
+	}
+}
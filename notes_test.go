@@ -0,0 +1,185 @@
+package main
+
+import (
+	"github.com/MakeNowJust/heredoc"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func NotesFixture() *TaskBox {
+	tb := &TaskBox{Lines: []string{
+		"- [ ] foo",
+		"  foo note",
+		"  \tmore",
+		"- [ ] bar",
+		"  bar note",
+		"baz",
+		"  not a note",
+	}}
+	tb.calculate()
+	tb.h = 10
+	return tb
+}
+
+func TestNotesHidden(t *testing.T) {
+	tb := NotesFixture()
+	assert.Equal(t, heredoc.Doc(`
+		> - [ ] foo
+		  - [ ] bar
+		  baz
+		    not a note
+	`), tb.String())
+	assert.Equal(t, []string{"foo note", "more"}, tb.CursorNotes())
+
+	tb.ToggleNotes()
+	assert.Nil(t, tb.CursorNotes())
+	assert.Equal(t, 7, len(tb.view))
+
+	tb.CursorDown()
+	tb.ToggleNotes()
+	assert.Equal(t, 0, tb.cursor)
+}
+
+func TestNotesSubTask(t *testing.T) {
+	tb := &TaskBox{Lines: []string{
+		"- [ ] foo",
+		"  foo note",
+		"  - [ ] child",
+		"  * item",
+	}}
+	tb.calculate()
+	tb.h = 10
+	assert.Equal(t, heredoc.Doc(`
+		> - [ ] foo
+		    - [ ] child
+		    * item
+	`), tb.String())
+	assert.Equal(t, []string{"foo note"}, tb.CursorNotes())
+}
+
+func TestNotesMoveWithTask(t *testing.T) {
+	tb := NotesFixture()
+	tb.MoveLineDown()
+	assert.Equal(t, []string{
+		"- [ ] bar",
+		"  bar note",
+		"- [ ] foo",
+		"  foo note",
+		"  \tmore",
+		"baz",
+		"  not a note",
+	}, tb.Lines)
+	assert.Equal(t, 1, tb.cursor)
+
+	tb.MoveLineUp()
+	assert.Equal(t, NotesFixture().Lines, tb.Lines)
+
+	tb.CopyLine()
+	assert.Equal(t, []string{
+		"- [ ] foo",
+		"  foo note",
+		"  \tmore",
+	}, tb.Lines[3:6])
+
+	tb.TaskDeleteKey()
+	assert.Equal(t, NotesFixture().Lines, tb.Lines)
+
+	tb.MoveLineToBottom()
+	assert.Equal(t, "- [ ] bar", tb.Lines[0])
+	assert.Equal(t, []string{
+		"- [ ] foo",
+		"  foo note",
+		"  \tmore",
+	}, tb.Lines[4:])
+}
+
+func TestNotesArchive(t *testing.T) {
+	tb := NotesFixture()
+	tb.CursorDown()
+	tb.ToggleComment()
+	assert.Equal(t, []string{
+		"<!-- - [ ] bar -->",
+		"<!--   bar note -->",
+	}, tb.Lines[3:5])
+	assert.Equal(t, "  bar note", ParseComment(tb.Lines[4]))
+
+	tb.EnterArchiveMode()
+	assert.Equal(t, heredoc.Doc(`
+		> - [ ] bar
+	`), tb.String())
+	tb.ToggleComment()
+	assert.Equal(t, NotesFixture().Lines, tb.Lines)
+}
+
+func TestNotesSort(t *testing.T) {
+	tb := NotesFixture()
+	tb.Sort("text")
+	assert.Equal(t, []string{
+		"- [ ] bar",
+		"  bar note",
+		"- [ ] foo",
+		"  foo note",
+		"  \tmore",
+		"baz",
+		"  not a note",
+	}, tb.Lines)
+}
+
+func TestNotesSortMarked(t *testing.T) {
+	tb := &TaskBox{Lines: []string{
+		"- [ ] c",
+		"- [ ] b",
+		"  note of b",
+		"text",
+		"- [ ] a",
+	}}
+	tb.calculate()
+	tb.h = 10
+	tb.ToggleMark() // c
+	tb.ToggleMark() // b
+	tb.CursorDown()
+	tb.ToggleMark() // a
+	assert.Nil(t, tb.Sort("text"))
+	assert.Equal(t, []string{
+		"- [ ] a",
+		"- [ ] b",
+		"  note of b",
+		"text",
+		"- [ ] c",
+	}, tb.Lines)
+}
+
+func TestEmptyNoteRemoved(t *testing.T) {
+	tb := NotesFixture()
+	tb.AddNote()
+	assert.True(t, tb.showNotes)
+	assert.Equal(t, "  ", tb.Lines[3])
+	tb.ExitEditMode()
+	assert.Equal(t, NotesFixture().Lines, tb.Lines)
+	assert.False(t, tb.showNotes)
+	i, _ := tb.SelectedLine()
+	assert.Equal(t, 0, i)
+
+	// Other blank lines are kept
+	tb.Lines = append(tb.Lines, "   ")
+	tb.calculate()
+	tb.CursorToLine(7)
+	tb.EnterEditMode()
+	tb.ExitEditMode()
+	assert.Equal(t, 8, len(tb.Lines))
+}
+
+func TestNotesYankPaste(t *testing.T) {
+	tb := NotesFixture()
+	tb.Yank(defaultRegister)
+	tb.Paste(defaultRegister, false)
+	assert.Equal(t, []string{
+		"- [ ] foo",
+		"  foo note",
+		"  \tmore",
+		"- [ ] foo",
+		"  foo note",
+		"  \tmore",
+		"- [ ] bar",
+	}, tb.Lines[:7])
+}
@@ -0,0 +1,154 @@
+package main
+
+/*
+Lines may be selected with visual mode (range from anchor line to
+cursor) and with marks toggled on separate lines. Both are kept
+as line indexes so they are shifted when lines are inserted, deleted
+or swapped.
+
+Bulk operations use Selection() which falls back to the line under
+cursor when nothing is selected.
+*/
+
+func (tb *TaskBox) ToggleVisual() {
+	if tb.visual {
+		tb.visual = false
+		return
+	}
+	i, _ := tb.SelectedLine()
+	if i < 0 {
+		return
+	}
+	tb.visual = true
+	tb.anchor = i
+}
+
+// Toggle mark on the line under cursor and go to the next line
+func (tb *TaskBox) ToggleMark() {
+	i, _ := tb.SelectedLine()
+	if i < 0 {
+		return
+	}
+	if tb.marked == nil {
+		tb.marked = make(map[int]bool)
+	}
+	if tb.marked[i] {
+		delete(tb.marked, i)
+	} else {
+		tb.marked[i] = true
+	}
+	tb.CursorDown()
+}
+
+func (tb *TaskBox) ClearSelection() {
+	tb.visual = false
+	tb.marked = nil
+}
+
+func (tb *TaskBox) HasSelection() bool {
+	return tb.visual || len(tb.marked) > 0
+}
+
+// View positions of visual range
+func (tb *TaskBox) visualRange() (int, int) {
+	if !tb.visual {
+		return -1, -1
+	}
+	for pos, index := range tb.view {
+		if index == tb.anchor {
+			if pos < tb.cursor {
+				return pos, tb.cursor
+			}
+			return tb.cursor, pos
+		}
+	}
+	// Anchor is filtered out
+	return tb.cursor, tb.cursor
+}
+
+func (tb *TaskBox) isSelectedAt(pos int) bool {
+	from, to := tb.visualRange()
+	return (pos >= from && pos <= to) || tb.marked[tb.view[pos]]
+}
+
+// View positions of selected lines
+func (tb *TaskBox) selectedPositions() []int {
+	var positions []int
+	if tb.HasSelection() {
+		for pos := range tb.view {
+			if tb.isSelectedAt(pos) {
+				positions = append(positions, pos)
+			}
+		}
+	} else if len(tb.view) > 0 {
+		positions = append(positions, tb.cursor)
+	}
+	return positions
+}
+
+// Indexes of selected visible lines in ascending order
+func (tb *TaskBox) Selection() []int {
+	positions := tb.selectedPositions()
+	indexes := make([]int, 0, len(positions))
+	for _, pos := range positions {
+		// Skip agenda group headers
+		if tb.view[pos] >= 0 {
+			indexes = append(indexes, tb.view[pos])
+		}
+	}
+	return indexes
+}
+
+func (tb *TaskBox) SelectionSize() int {
+	if !tb.HasSelection() {
+		return 0
+	}
+	return len(tb.selectedPositions())
+}
+
+// Keep selection on the same lines after line is inserted (delta = 1)
+// or deleted (delta = -1) at index i
+func (tb *TaskBox) shiftSelection(i, delta int) {
+	if tb.visual {
+		switch {
+		case delta < 0 && tb.anchor == i:
+			tb.visual = false
+		case tb.anchor >= i:
+			tb.anchor += delta
+		}
+	}
+	if len(tb.marked) == 0 {
+		return
+	}
+	marked := make(map[int]bool)
+	for index := range tb.marked {
+		switch {
+		case index < i:
+			marked[index] = true
+		case delta < 0 && index == i:
+			// Deleted
+		default:
+			marked[index+delta] = true
+		}
+	}
+	tb.marked = marked
+}
+
+func (tb *TaskBox) swapSelection(i, j int) {
+	if tb.visual {
+		if tb.anchor == i {
+			tb.anchor = j
+		} else if tb.anchor == j {
+			tb.anchor = i
+		}
+	}
+	if tb.marked[i] != tb.marked[j] {
+		if tb.marked[i] {
+			delete(tb.marked, i)
+			tb.marked[j] = true
+		} else {
+			delete(tb.marked, j)
+			tb.marked[i] = true
+		}
+	}
+}
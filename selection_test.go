@@ -0,0 +1,213 @@
+package main
+
+import (
+	"github.com/MakeNowJust/heredoc"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestVisualSelection(t *testing.T) {
+	tb := TaskBoxFixture(5)
+	assert.Equal(t, []int{0}, tb.Selection())
+	assert.Equal(t, 0, tb.SelectionSize())
+
+	tb.CursorDown()
+	tb.ToggleVisual()
+	tb.CursorDown()
+	tb.CursorDown()
+	assert.Equal(t, []int{1, 2, 3}, tb.Selection())
+	assert.Equal(t, tb.String(), heredoc.Doc(`
+		  foo
+		* bar
+		* baz
+		> qux
+		  quux
+	`))
+	tb.CursorUp()
+	tb.CursorUp()
+	tb.CursorUp()
+	assert.Equal(t, []int{0, 1}, tb.Selection())
+
+	tb.ToggleVisual()
+	assert.False(t, tb.HasSelection())
+	assert.Equal(t, []int{0}, tb.Selection())
+}
+
+func TestMarks(t *testing.T) {
+	tb := TaskBoxFixture(5)
+	tb.ToggleMark()
+	tb.CursorDown()
+	tb.ToggleMark()
+	assert.Equal(t, []int{0, 2}, tb.Selection())
+	assert.Equal(t, 2, tb.SelectionSize())
+	assert.Equal(t, tb.String(), heredoc.Doc(`
+		* foo
+		  bar
+		* baz
+		> qux
+		  quux
+	`))
+
+	// Marks follow lines
+	tb.InsertLine(1, "new")
+	tb.calculate()
+	assert.Equal(t, []int{0, 3}, tb.Selection())
+	tb.DeleteLine(0)
+	tb.calculate()
+	assert.Equal(t, []int{2}, tb.Selection())
+
+	tb.cursor = 2
+	tb.ToggleMark()
+	assert.False(t, tb.HasSelection())
+}
+
+func TestBulkToggleAndDelete(t *testing.T) {
+	tb := TaskBoxWithUndo()
+	tb.Lines = []string{"- [ ] Foo", "- [ ] Bar", "Baz", "- [x] Qux", "- [ ] Quux"}
+	tb.undo.PutState()
+	tb.calculate()
+	tb.h = 5
+
+	tb.Exec("visual")
+	tb.Exec("down")
+	tb.Exec("down")
+	tb.Exec("down")
+	tb.Exec("toggle")
+	tb.undo.PutState()
+	assert.False(t, tb.HasSelection())
+	assert.Equal(t, tb.InnerString(), heredoc.Doc(`
+		- [x] Foo
+		- [x] Bar
+		Baz
+		- [ ] Qux
+		- [ ] Quux
+	`))
+
+	tb.Exec("undo")
+	assert.Equal(t, tb.InnerString(), heredoc.Doc(`
+		- [ ] Foo
+		- [ ] Bar
+		Baz
+		- [x] Qux
+		- [ ] Quux
+	`))
+
+	tb.cursor = 1
+	tb.Exec("mark")
+	tb.Exec("mark")
+	tb.Exec("down")
+	tb.Exec("mark")
+	tb.Exec("delete")
+	assert.Equal(t, tb.InnerString(), heredoc.Doc(`
+		- [ ] Foo
+		- [x] Qux
+	`))
+}
+
+func TestBulkArchive(t *testing.T) {
+	tb := TaskBoxFixture(4)
+	tb.ToggleMark()
+	tb.CursorDown()
+	tb.ToggleMark()
+	tb.ToggleComment()
+	assert.Equal(t, tb.InnerString(), heredoc.Doc(`
+		<!-- foo -->
+		bar
+		<!-- baz -->
+		qux
+	`))
+	assert.Equal(t, tb.String(), heredoc.Doc(`
+		  bar
+		> qux
+	`))
+}
+
+func TestBulkMove(t *testing.T) {
+	tb := TaskBoxFixture(5)
+	tb.cursor = 1
+	tb.ToggleVisual()
+	tb.CursorDown()
+	tb.MoveLineUp()
+	assert.Equal(t, tb.String(), heredoc.Doc(`
+		* bar
+		> baz
+		  foo
+		  qux
+		  quux
+	`))
+	// Top reached
+	tb.MoveLineUp()
+	assert.Equal(t, []int{0, 1}, tb.Selection())
+
+	tb.MoveLineDown()
+	tb.MoveLineDown()
+	tb.MoveLineDown()
+	tb.MoveLineDown()
+	assert.Equal(t, tb.String(), heredoc.Doc(`
+		  foo
+		  qux
+		  quux
+		* bar
+		> baz
+	`))
+}
+
+func TestBulkMoveMarked(t *testing.T) {
+	tb := TaskBoxFixture(5)
+	tb.cursor = 1
+	tb.ToggleMark()
+	tb.CursorDown()
+	tb.ToggleMark()
+	tb.MoveLineDown()
+	assert.Equal(t, tb.String(), heredoc.Doc(`
+		  foo
+		  baz
+		* bar
+		  quux
+		> qux
+	`))
+}
+
+func TestCopyMarkedApart(t *testing.T) {
+	tb := TaskBoxFixture(3)
+	tb.ToggleMark()
+	tb.CursorDown()
+	tb.ToggleMark()
+	tb.CopyLine()
+	assert.Equal(t, tb.InnerString(), heredoc.Doc(`
+		foo
+		foo
+		bar
+		baz
+		baz
+	`))
+}
+
+func TestBulkMoveToBottomAndCopy(t *testing.T) {
+	tb := TaskBoxFixture(5)
+	tb.ToggleMark()
+	tb.CursorDown()
+	tb.ToggleMark()
+	tb.MoveLineToBottom()
+	assert.Equal(t, tb.InnerString(), heredoc.Doc(`
+		bar
+		qux
+		quux
+		foo
+		baz
+	`))
+
+	tb.cursor = 1
+	tb.ToggleVisual()
+	tb.CursorDown()
+	tb.CopyLine()
+	assert.Equal(t, tb.InnerString(), heredoc.Doc(`
+		bar
+		qux
+		quux
+		qux
+		quux
+		foo
+		baz
+	`))
+}
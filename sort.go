@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+/*
+Sorting reorders task lines of the selection or, without selection,
+of the section under cursor. Section is bounded by headings and
+workspace file markers. Notes are moved with their tasks. Other lines
+(headings, text, archived tasks) stay in place among sorted tasks.
+Sort is stable so tasks with equal keys keep their order. Tasks
+without created: date are created when history log says so.
+*/
+
+var sortKeys = []struct {
+	name string
+	less func(a, b Task) bool
+}{
+	{"status", func(a, b Task) bool {
+		return a.Status == StatusOpen && b.Status != StatusOpen
+	}},
+	{"priority", func(a, b Task) bool {
+		return a.Priority > b.Priority
+	}},
+	{"due", func(a, b Task) bool {
+		return dateLess(a.Due, b.Due)
+	}},
+	{"text", func(a, b Task) bool {
+		return strings.ToLower(a.Description) < strings.ToLower(b.Description)
+	}},
+	{"created", func(a, b Task) bool {
+		return dateLess(a.Created, b.Created)
+	}},
+}
+
+// Tasks without date go last
+func dateLess(a, b time.Time) bool {
+	if a.IsZero() != b.IsZero() {
+		return !a.IsZero()
+	}
+	return a.Before(b)
+}
+
+func sortKeyNames() []string {
+	names := make([]string, len(sortKeys))
+	for i, k := range sortKeys {
+		names[i] = k.name
+	}
+	return names
+}
+
+// Line indexes of the section under cursor
+func (tb *TaskBox) section() []int {
+	i, _ := tb.SelectedLine()
+	if i < 0 {
+		return nil
+	}
+	isBound := func(s string) bool {
+		return isHeading(s) || lineTypeOf(s) == lineFile
+	}
+	from, to := i, i
+	if isBound(tb.Lines[i]) {
+		from++
+	}
+	for from > 0 && !isBound(tb.Lines[from-1]) {
+		from--
+	}
+	for to+1 < len(tb.Lines) && !isBound(tb.Lines[to+1]) {
+		to++
+	}
+	var indexes []int
+	for k := from; k <= to; k++ {
+		indexes = append(indexes, k)
+	}
+	return indexes
+}
+
+// Time of the first created event of each task key
+func (tb *TaskBox) createdTimes() (map[string]time.Time, error) {
+	created := make(map[string]time.Time)
+	if tb.path == "" {
+		return created, nil
+	}
+	history, err := tb.history()
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range history {
+		if _, ok := created[e.Key]; !ok && e.Event == EventCreated {
+			created[e.Key] = e.Time
+		}
+	}
+	return created, nil
+}
+
+func (tb *TaskBox) Sort(key string) error {
+	var less func(a, b Task) bool
+	for _, k := range sortKeys {
+		if k.name == key {
+			less = k.less
+		}
+	}
+	if less == nil {
+		return fmt.Errorf("Unknown sort key: %s (%s)",
+			key, strings.Join(sortKeyNames(), ","))
+	}
+	var indexes []int
+	if tb.HasSelection() {
+		indexes = tb.Selection()
+	} else {
+		indexes = tb.section()
+	}
+	indexes = tb.withNotes(indexes)
+	// Tasks are sorted with their notes
+	created, err := tb.createdTimes()
+	if err != nil {
+		return err
+	}
+	var tasks []Task
+	var blocks [][]string
+	starts := make(map[int]int) // first line of block to its number
+	for _, i := range indexes {
+		if lineTypeOf(tb.Lines[i]) == lineTask {
+			starts[i] = len(tasks)
+			task := ParseTask(tb.Lines[i])
+			if task.Created.IsZero() {
+				task.Created = created[taskKey(task)]
+			}
+			tasks = append(tasks, task)
+			block := make([]string, tb.blockEnd(i)-i+1)
+			copy(block, tb.Lines[i:])
+			blocks = append(blocks, block)
+		}
+	}
+	order := make([]int, len(tasks))
+	for k := range order {
+		order[k] = k
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return less(tasks[order[a]], tasks[order[b]])
+	})
+	if len(indexes) == 0 {
+		return nil
+	}
+	// Blocks differ in size, so lines between marked ones may shift
+	first, last := indexes[0], indexes[len(indexes)-1]
+	var lines []string
+	for i := first; i <= last; i++ {
+		if k, ok := starts[i]; ok {
+			lines = append(lines, blocks[order[k]]...)
+			i += len(blocks[k]) - 1
+		} else {
+			lines = append(lines, tb.Lines[i])
+		}
+	}
+	for n, s := range lines {
+		tb.UpdateLine(first+n, s)
+	}
+	tb.ClearSelection()
+	tb.calculate()
+	return nil
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"github.com/MakeNowJust/heredoc"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func SortFixture() *TaskBox {
+	tb := &TaskBox{Lines: []string{
+		"# One",
+		"- [x] foo created:2026-01-03",
+		"- [ ] Bar due:2026-02-01",
+		"- [ ] baz !! created:2026-01-01",
+		"<!-- - [ ] old -->",
+		"- [ ] qux due:2026-01-01",
+		"# Two",
+		"- [ ] b",
+		"- [ ] a",
+	}}
+	tb.calculate()
+	tb.h = 10
+	tb.CursorToLine(1)
+	return tb
+}
+
+func TestSortSection(t *testing.T) {
+	cases := []struct {
+		key      string
+		expected string
+	}{
+		{"status", heredoc.Doc(`
+			- [ ] Bar due:2026-02-01
+			- [ ] baz !! created:2026-01-01
+			- [ ] qux due:2026-01-01
+			- [x] foo created:2026-01-03
+		`)},
+		{"priority", heredoc.Doc(`
+			- [ ] baz !! created:2026-01-01
+			- [x] foo created:2026-01-03
+			- [ ] Bar due:2026-02-01
+			- [ ] qux due:2026-01-01
+		`)},
+		{"due", heredoc.Doc(`
+			- [ ] qux due:2026-01-01
+			- [ ] Bar due:2026-02-01
+			- [x] foo created:2026-01-03
+			- [ ] baz !! created:2026-01-01
+		`)},
+		{"text", heredoc.Doc(`
+			- [ ] Bar due:2026-02-01
+			- [ ] baz !! created:2026-01-01
+			- [x] foo created:2026-01-03
+			- [ ] qux due:2026-01-01
+		`)},
+		{"created", heredoc.Doc(`
+			- [ ] baz !! created:2026-01-01
+			- [x] foo created:2026-01-03
+			- [ ] Bar due:2026-02-01
+			- [ ] qux due:2026-01-01
+		`)},
+	}
+	for _, c := range cases {
+		tb := SortFixture()
+		assert.Nil(t, tb.Sort(c.key))
+		tasks := []string{tb.Lines[1], tb.Lines[2], tb.Lines[3], tb.Lines[5]}
+		got := ""
+		for _, s := range tasks {
+			got += s + "\n"
+		}
+		assert.Equal(t, c.expected, got, c.key)
+		// Other lines keep their places
+		assert.Equal(t, "# One", tb.Lines[0])
+		assert.Equal(t, "<!-- - [ ] old -->", tb.Lines[4])
+		assert.Equal(t, []string{"# Two", "- [ ] b", "- [ ] a"}, tb.Lines[6:])
+	}
+}
+
+func TestSortSelection(t *testing.T) {
+	tb := SortFixture()
+	tb.CursorToLine(7)
+	tb.ToggleVisual()
+	tb.CursorDown()
+	assert.Nil(t, tb.Sort("text"))
+	assert.Equal(t, []string{"# Two", "- [ ] a", "- [ ] b"}, tb.Lines[6:])
+	assert.Equal(t, "- [x] foo created:2026-01-03", tb.Lines[1])
+	assert.False(t, tb.HasSelection())
+}
+
+func TestSortUnknownKey(t *testing.T) {
+	tb := SortFixture()
+	assert.EqualError(t, tb.Sort("foo"),
+		"Unknown sort key: foo (status,priority,due,text,created)")
+}
+
+func TestSortCreatedFromHistory(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "sort")
+	defer os.RemoveAll(dir)
+	tb := &TaskBox{Lines: []string{"- [ ] a", "- [ ] b !", "- [ ] c"}}
+	tb.path = filepath.Join(dir, "TODO.md")
+	tb.calculate()
+	tb.h = 10
+	day := time.Date(2026, 1, 1, 10, 0, 0, 0, time.Local)
+	for i, key := range []string{"b", "a"} {
+		AppendHistory(tb.path+historyExt, HistoryEntry{
+			day.AddDate(0, 0, i), EventCreated, key, key})
+	}
+	assert.Nil(t, tb.Sort("created"))
+	assert.Equal(t, []string{"- [ ] b !", "- [ ] a", "- [ ] c"}, tb.Lines)
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+/*
+Statistics of task list. Completion dates and burndown are taken
+from history log (see history.go) so they cover only tasks changed
+since history is recorded.
+*/
+
+const (
+	statsDays  = 14 // days in completed per day and burndown
+	statsWeeks = 8
+	barWidth   = 40
+)
+
+type GroupStats struct {
+	Name         string
+	Open, Closed int
+}
+
+func (g GroupStats) Percent() int {
+	if g.Open+g.Closed == 0 {
+		return 0
+	}
+	return g.Closed * 100 / (g.Open + g.Closed)
+}
+
+type Stats struct {
+	Total    GroupStats
+	Archived int
+	Sections []GroupStats
+	Tags     []GroupStats
+	Days     []time.Time // last statsDays days
+	PerDay   []int       // tasks completed on each of Days
+	Weeks    []time.Time // Mondays of last statsWeeks weeks
+	PerWeek  []int
+	Burndown []int // open tasks at the end of each of Days
+}
+
+func (g *GroupStats) add(task Task) {
+	if task.Status == StatusClosed {
+		g.Closed++
+	} else {
+		g.Open++
+	}
+}
+
+func ComputeStats(lines []string, history []HistoryEntry, today time.Time) *Stats {
+	s := &Stats{}
+	tags := make(map[string]*GroupStats)
+	var section *GroupStats
+	for _, line := range lines {
+		if isHeading(line) {
+			s.Sections = append(s.Sections,
+				GroupStats{Name: strings.TrimSpace(strings.TrimLeft(line, "#"))})
+			section = &s.Sections[len(s.Sections)-1]
+			continue
+		}
+		if content := lineContent(line); lineTypeOf(line) == lineComment &&
+			lineTypeOf(content) == lineTask {
+			s.Archived++
+			continue
+		}
+		if lineTypeOf(line) != lineTask {
+			continue
+		}
+		task := ParseTask(line)
+		s.Total.add(task)
+		if section == nil {
+			s.Sections = append(s.Sections, GroupStats{Name: "-"})
+			section = &s.Sections[len(s.Sections)-1]
+		}
+		section.add(task)
+		for _, tag := range task.Tags {
+			if tags[tag] == nil {
+				tags[tag] = &GroupStats{Name: "#" + tag}
+			}
+			tags[tag].add(task)
+		}
+	}
+	for _, g := range tags {
+		s.Tags = append(s.Tags, *g)
+	}
+	sort.Slice(s.Tags, func(i, j int) bool { return s.Tags[i].Name < s.Tags[j].Name })
+
+	from := today.AddDate(0, 0, 1-statsDays)
+	for d := 0; d < statsDays; d++ {
+		s.Days = append(s.Days, from.AddDate(0, 0, d))
+	}
+	monday := today.AddDate(0, 0, -(int(today.Weekday())+6)%7)
+	for w := statsWeeks - 1; w >= 0; w-- {
+		s.Weeks = append(s.Weeks, monday.AddDate(0, 0, -7*w))
+	}
+	s.PerDay = make([]int, statsDays)
+	s.PerWeek = make([]int, statsWeeks)
+	s.Burndown = make([]int, statsDays)
+	for d := range s.Burndown {
+		s.Burndown[d] = s.Total.Open
+	}
+	closed := make(map[string]bool) // by task key
+	for _, e := range history {
+		t := e.Time.Local()
+		if e.Event == EventCompleted {
+			for d, day := range s.Days {
+				if !t.Before(day) && t.Before(day.AddDate(0, 0, 1)) {
+					s.PerDay[d]++
+				}
+			}
+			for w, week := range s.Weeks {
+				if !t.Before(week) && t.Before(week.AddDate(0, 0, 7)) {
+					s.PerWeek[w]++
+				}
+			}
+		}
+		// Go back in time from current number of open tasks. Deleted
+		// and archived tasks count only if they were open
+		var delta int
+		switch e.Event {
+		case EventCompleted:
+			delta = 1
+			closed[e.Key] = true
+		case EventCreated, EventReopened:
+			delta = -1
+			closed[e.Key] = false
+		case EventDeleted, EventArchived:
+			if !closed[e.Key] {
+				delta = 1
+			}
+		case EventUnarchived:
+			if !closed[e.Key] {
+				delta = -1
+			}
+		}
+		for d, day := range s.Days {
+			if !t.Before(day.AddDate(0, 0, 1)) {
+				s.Burndown[d] += delta
+			}
+		}
+	}
+	return s
+}
+
+func bar(n, max int) string {
+	if max <= 0 || n <= 0 {
+		return ""
+	}
+	return strings.Repeat("#", (n*barWidth+max-1)/max)
+}
+
+func maxInt(values []int) int {
+	max := 0
+	for _, v := range values {
+		if v > max {
+			max = v
+		}
+	}
+	return max
+}
+
+// Text report
+func (s *Stats) Report() []string {
+	var r []string
+	add := func(format string, args ...interface{}) {
+		r = append(r, fmt.Sprintf(format, args...))
+	}
+	add("Tasks: %d open, %d closed, %d archived. Done %d%%",
+		s.Total.Open, s.Total.Closed, s.Archived, s.Total.Percent())
+	for _, groups := range []struct {
+		title string
+		list  []GroupStats
+	}{{"Sections", s.Sections}, {"Tags", s.Tags}} {
+		if len(groups.list) == 0 {
+			continue
+		}
+		add("")
+		add("%s:", groups.title)
+		for _, g := range groups.list {
+			add("  %-30s %4d/%-4d %3d%%", g.Name, g.Closed, g.Open+g.Closed,
+				g.Percent())
+		}
+	}
+	add("")
+	add("Completed per day:")
+	max := maxInt(s.PerDay)
+	for d, day := range s.Days {
+		add("  %s %3d %s", day.Format(DateFormat), s.PerDay[d], bar(s.PerDay[d], max))
+	}
+	add("")
+	add("Completed per week:")
+	max = maxInt(s.PerWeek)
+	for w, week := range s.Weeks {
+		add("  %s %3d %s", week.Format(DateFormat), s.PerWeek[w], bar(s.PerWeek[w], max))
+	}
+	add("")
+	add("Burndown (open tasks):")
+	max = maxInt(s.Burndown)
+	for d, day := range s.Days {
+		add("  %s %3d %s", day.Format(DateFormat), s.Burndown[d], bar(s.Burndown[d], max))
+	}
+	return r
+}
+
+func (tb *TaskBox) Stats() (*Stats, error) {
+	history, err := tb.history()
+	if err != nil {
+		return nil, err
+	}
+	return ComputeStats(tb.Lines, history, today()), nil
+}
+
+func (tb *TaskBox) ShowStats() error {
+	s, err := tb.Stats()
+	if err != nil {
+		return err
+	}
+	tb.recordSteps()
+	pager("Statistics of "+tb.path, s.Report())
+	return nil
+}
+
+// Print statistics of task file or workspace directory
+func PrintStats(path string) error {
+	tb := &TaskBox{}
+	if isDir(path) {
+		if err := tb.LoadWorkspace(path); err != nil {
+			return err
+		}
+	} else if err := tb.Load(path); err != nil {
+		return err
+	}
+	s, err := tb.Stats()
+	if err != nil {
+		return err
+	}
+	fmt.Println(strings.Join(s.Report(), "\n"))
+	return nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"github.com/MakeNowJust/heredoc"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestComputeStats(t *testing.T) {
+	lines := strings.Split(heredoc.Doc(`
+		- [ ] loose
+		# Work
+		- [x] foo #dev
+		- [ ] bar #dev
+		<!-- - [x] old -->
+		## Home
+		- [x] baz #dev #home
+		  note`), "\n")
+	today := time.Date(2020, 5, 14, 0, 0, 0, 0, time.Local) // Thursday
+	at := func(day int) time.Time {
+		return time.Date(2020, 5, day, 12, 0, 0, 0, time.Local)
+	}
+	history := []HistoryEntry{
+		{Time: at(1), Event: EventCompleted},
+		{Time: at(12), Event: EventCreated},
+		{Time: at(13), Event: EventCompleted},
+		{Time: at(14), Event: EventCompleted},
+	}
+	s := ComputeStats(lines, history, today)
+
+	assert.Equal(t, GroupStats{Open: 2, Closed: 2}, s.Total)
+	assert.Equal(t, 50, s.Total.Percent())
+	assert.Equal(t, 1, s.Archived)
+	assert.Equal(t, []GroupStats{
+		{"-", 1, 0}, {"Work", 1, 1}, {"Home", 0, 1},
+	}, s.Sections)
+	assert.Equal(t, []GroupStats{{"#dev", 1, 2}, {"#home", 0, 1}}, s.Tags)
+
+	assert.Equal(t, statsDays, len(s.Days))
+	assert.Equal(t, today, s.Days[statsDays-1])
+	assert.Equal(t, []int{1, 1}, s.PerDay[statsDays-2:])
+	assert.Equal(t, 1, s.PerDay[0]) // May 1
+	assert.Equal(t, time.Date(2020, 5, 11, 0, 0, 0, 0, time.Local),
+		s.Weeks[statsWeeks-1])
+	assert.Equal(t, []int{1, 0, 2}, s.PerWeek[statsWeeks-3:])
+	assert.Equal(t, []int{3, 3, 4, 3, 2}, s.Burndown[statsDays-5:])
+
+	r := s.Report()
+	assert.Equal(t, "Tasks: 2 open, 2 closed, 1 archived. Done 50%", r[0])
+}
+
+func TestBurndownRemovedTasks(t *testing.T) {
+	today := time.Date(2020, 5, 14, 0, 0, 0, 0, time.Local)
+	at := func(day int) time.Time {
+		return time.Date(2020, 5, day, 12, 0, 0, 0, time.Local)
+	}
+	history := []HistoryEntry{
+		{Time: at(10), Event: EventCreated, Key: "b"},
+		{Time: at(11), Event: EventCompleted, Key: "c"},
+		{Time: at(12), Event: EventDeleted, Key: "b"},
+		{Time: at(12), Event: EventArchived, Key: "c"},
+		{Time: at(13), Event: EventArchived, Key: "d"},
+	}
+	s := ComputeStats([]string{"- [ ] a"}, history, today)
+	assert.Equal(t, []int{3, 4, 3, 2, 1, 1}, s.Burndown[statsDays-6:])
+}
@@ -2,7 +2,9 @@ package main
 
 import (
 	"fmt"
+	"regexp"
 	"strings"
+	"time"
 )
 
 const TaskPrefix string = "- [ ] "
@@ -13,12 +15,15 @@ const (
 	StatusAll    Status = 0
 	StatusOpen          = ' '
 	StatusClosed        = 'x'
+	// Filter only. Open tasks which are not blocked
+	StatusActionable = 'a'
 )
 
 var statusToString = map[Status]string{
-	StatusAll:    "All",
-	StatusOpen:   "Open",
-	StatusClosed: "Closed",
+	StatusAll:        "All",
+	StatusOpen:       "Open",
+	StatusClosed:     "Closed",
+	StatusActionable: "Actionable",
 }
 
 func (s Status) String() string {
@@ -37,8 +42,41 @@ func StatusFromString(s string) Status {
 type Task struct {
 	Description string
 	Status      Status
+	Priority    int       // 0 (none) to MaxPriority, parsed from Description
+	Due         time.Time // parsed from Description
+	Created     time.Time // parsed from Description
+	Tags        []string  // #tags parsed from Description
+	ID          string    // stable id parsed from Description
+	BlockedBy   []string  // ids of tasks this one depends on
 }
 
+const (
+	MaxPriority = 3
+	DateFormat  = "2006-01-02"
+)
+
+/*
+Priority may be written in description as
+
+	!, !!, !!!        low, medium, high
+	(C), (B), (A)     at the beginning of description
+	p:3, p:2, p:1
+
+Due date is written as due:2020-01-31, creation date as
+created:2020-01-31, tags as #tag, task id as id:k3x9qa and
+dependencies as blocked-by:k3x9qa,m2p7zz
+*/
+var (
+	reBangs    = regexp.MustCompile(`(^|\s)(!{1,3})(\s|$)`)
+	reLetter   = regexp.MustCompile(`^\(([A-C])\)(\s|$)`)
+	rePriority = regexp.MustCompile(`(^|\s)p:([1-3])(\s|$)`)
+	reDue      = regexp.MustCompile(`(^|\s)due:(\d{4}-\d{2}-\d{2})(\s|$)`)
+	reCreated  = regexp.MustCompile(`(^|\s)created:(\d{4}-\d{2}-\d{2})(\s|$)`)
+	reTag      = regexp.MustCompile(`(^|\s)#([\w-]+)`)
+	reID       = regexp.MustCompile(`(^|\s)id:([0-9a-z]+)(\s|$)`)
+	reBlocked  = regexp.MustCompile(`(^|\s)blocked-by:([0-9a-z]+(,[0-9a-z]+)*)`)
+)
+
 func (task *Task) String() string {
 	return fmt.Sprintf("- [%c] %s", task.Status, task.Description)
 }
@@ -55,5 +93,117 @@ func ParseTask(s string) Task {
 	} else {
 		t.Description = ""
 	}
+	t.Priority, _ = parsePriority(t.Description)
+	if m := reDue.FindStringSubmatch(t.Description); m != nil {
+		t.Due, _ = time.ParseInLocation(DateFormat, m[2], time.Local)
+	}
+	if m := reCreated.FindStringSubmatch(t.Description); m != nil {
+		t.Created, _ = time.ParseInLocation(DateFormat, m[2], time.Local)
+	}
+	for _, m := range reTag.FindAllStringSubmatch(t.Description, -1) {
+		t.Tags = append(t.Tags, m[2])
+	}
+	if m := reID.FindStringSubmatch(t.Description); m != nil {
+		t.ID = m[2]
+	}
+	for _, m := range reBlocked.FindAllStringSubmatch(t.Description, -1) {
+		t.BlockedBy = append(t.BlockedBy, strings.Split(m[2], ",")...)
+	}
 	return t
 }
+
+// Return priority and location of its marker in description
+func parsePriority(s string) (int, []int) {
+	if m := reLetter.FindStringSubmatchIndex(s); m != nil {
+		return int('C'-s[m[2]]) + 1, []int{m[0], m[3] + 1}
+	}
+	if m := reBangs.FindStringSubmatchIndex(s); m != nil {
+		return m[5] - m[4], m[4:6]
+	}
+	if m := rePriority.FindStringSubmatchIndex(s); m != nil {
+		return MaxPriority + 1 - int(s[m[4]]-'0'), []int{m[4] - 2, m[5]}
+	}
+	return 0, nil
+}
+
+func (task *Task) HasTag(tag string) bool {
+	for _, t := range task.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// Change priority keeping the style of existing marker
+
+func (task *Task) SetPriority(p int) {
+	if p < 0 {
+		p = 0
+	}
+	if p > MaxPriority {
+		p = MaxPriority
+	}
+	s := task.Description
+	_, loc := parsePriority(s)
+	switch {
+	case loc == nil && p > 0:
+		s = strings.TrimRight(s, " ") + " " + strings.Repeat("!", p)
+		s = strings.TrimLeft(s, " ")
+	case loc == nil:
+		// Nothing to remove
+	case p == 0:
+		s = removeToken(s, loc[0], loc[1])
+	default:
+		var marker string
+		old := s[loc[0]:loc[1]]
+		switch {
+		case strings.HasPrefix(old, "!"):
+			marker = strings.Repeat("!", p)
+		case strings.HasPrefix(old, "p:"):
+			marker = fmt.Sprintf("p:%d", MaxPriority+1-p)
+		default:
+			marker = fmt.Sprintf("(%c)", 'C'-p+1)
+		}
+		s = s[:loc[0]] + marker + s[loc[1]:]
+	}
+	task.Description = s
+	task.Priority = p
+}
+
+// Remove token from s with one space around it
+func removeToken(s string, from, to int) string {
+	if to < len(s) && s[to] == ' ' {
+		to++
+	} else if from > 0 && s[from-1] == ' ' {
+		from--
+	}
+	return s[:from] + s[to:]
+}
+
+// Replace, add or remove (zero date) due date
+func (task *Task) SetDue(due time.Time) {
+	s := task.Description
+	if m := reDue.FindStringSubmatchIndex(s); m != nil {
+		s = removeToken(s, m[4]-len("due:"), m[5])
+	}
+	if !due.IsZero() {
+		s = strings.TrimLeft(
+			strings.TrimRight(s, " ")+" due:"+due.Format(DateFormat), " ")
+	}
+	task.Description = s
+	task.Due = due
+}
+
+// Replace, add or remove (id = "") task id
+func (task *Task) SetID(id string) {
+	s := task.Description
+	if m := reID.FindStringSubmatchIndex(s); m != nil {
+		s = removeToken(s, m[4]-len("id:"), m[5])
+	}
+	if id != "" {
+		s = strings.TrimLeft(strings.TrimRight(s, " ")+" id:"+id, " ")
+	}
+	task.Description = s
+	task.ID = id
+}
@@ -39,3 +39,47 @@ func TestParseTask(t *testing.T) {
 		Status:      StatusClosed,
 	})
 }
+
+func TestParsePriority(t *testing.T) {
+	assert.Equal(t, 0, ParseTask("- [ ] foo").Priority)
+	assert.Equal(t, 1, ParseTask("- [ ] foo !").Priority)
+	assert.Equal(t, 3, ParseTask("- [ ] !!! foo").Priority)
+	assert.Equal(t, 0, ParseTask("- [ ] foo!!").Priority)
+	assert.Equal(t, 3, ParseTask("- [ ] (A) foo").Priority)
+	assert.Equal(t, 1, ParseTask("- [ ] (C) foo").Priority)
+	assert.Equal(t, 2, ParseTask("- [ ] foo p:2").Priority)
+	assert.Equal(t, 3, ParseTask("- [ ] foo p:1").Priority)
+
+	task := ParseTask("- [ ] foo due:2026-03-01")
+	assert.Equal(t, "2026-03-01", task.Due.Format(DateFormat))
+	assert.True(t, ParseTask("- [ ] foo").Due.IsZero())
+}
+
+func TestSetPriority(t *testing.T) {
+	cases := []struct {
+		line     string
+		priority int
+		expected string
+	}{
+		{"- [ ] foo", 2, "foo !!"},
+		{"- [ ] foo !", 3, "foo !!!"},
+		{"- [ ] foo !! bar", 0, "foo bar"},
+		{"- [ ] (B) foo", 3, "(A) foo"},
+		{"- [ ] (A) foo", 0, "foo"},
+		{"- [ ] foo p:3", 2, "foo p:2"},
+		{"- [ ] foo p:3", 0, "foo"},
+		{"- [ ] foo !!!", 5, "foo !!!"},
+		{"- [ ] foo", -1, "foo"},
+	}
+	for _, c := range cases {
+		task := ParseTask(c.line)
+		task.SetPriority(c.priority)
+		assert.Equal(t, c.expected, task.Description, c.line)
+	}
+}
+
+func TestParseTags(t *testing.T) {
+	assert.Nil(t, ParseTask("- [ ] foo").Tags)
+	assert.Equal(t, []string{"work", "long-term"},
+		ParseTask("- [ ] #work foo #long-term bar#baz").Tags)
+}
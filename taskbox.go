@@ -5,7 +5,9 @@ import (
 	"github.com/nsf/termbox-go"
 	"github.com/smetana/editbox-go"
 	"regexp"
+	"sort"
 	"strings"
+	"time"
 )
 
 type mode int
@@ -14,14 +16,18 @@ const (
 	modeTask mode = iota
 	modeEdit
 	modeArchive
+	modeAgenda
+	modeCalendar
 	modeExit
 )
 
 func (m mode) String() string {
 	return map[mode]string{
-		modeTask:    "Task",
-		modeEdit:    "Edit",
-		modeArchive: "Archive",
+		modeTask:     "Task",
+		modeEdit:     "Edit",
+		modeArchive:  "Archive",
+		modeAgenda:   "Agenda",
+		modeCalendar: "Calendar",
 	}[m]
 }
 
@@ -33,6 +39,7 @@ const (
 	lineComment
 	lineCommentOpen
 	lineCommentClose
+	lineFile
 )
 
 var (
@@ -44,6 +51,8 @@ var (
 
 func lineTypeOf(s string) lineType {
 	switch {
+	case reFile.MatchString(s):
+		return lineFile
 	case reCommentOpen.MatchString(s):
 		return lineCommentOpen
 	case reCommentClose.MatchString(s):
@@ -63,22 +72,95 @@ type TaskBox struct {
 	modified bool
 	view     []int
 	filter   Status
+	tag      string // show only tasks with tag
 	x, y     int
 	w, h     int
 	cursor   int
 	scroll   int
 	editor   *editbox.Editbox
 	lastX    int
+	editLine string    // line before edit
+	editFrom mode      // mode to return after edit
+	day      time.Time // selected day of calendar
 	undo     *Undo
+	// Name of the last executed command
+	lastCommand string
+	// Message to show in status line
+	message string
+	// Selection. See selection.go
+	visual bool
+	anchor int
+	marked map[int]bool
+	// Yanked lines. See clipboard.go
+	registers map[rune][]string
+	// Open files. See buffers.go
+	buffers   []*Buffer
+	current   int
+	workspace bool // directories are open as workspace
+	// History events to write on save. See history.go
+	events      []*pendingEvent
+	stateEvents []*pendingEvent // not in undo state yet
+	// View tasks ordered by priority and due date
+	sorted     bool
+	sortedEdit bool // sorted view is off while editing
+	timer      *Timer
+	showNotes  bool // show notes in the list
+	addedNote  int  // line of note added by AddNote, 0 if none
+	notesShown bool // showNotes before AddNote
+	openIDs    map[string]bool
+	mouse      mouseState
+	keys       keySequence  // pending count and prefix of task keys
+	marks      map[rune]int // line of mark a-z
+	macro      macroState
+	macros     map[rune][]string // macro steps by register a-z
 }
 
 func (tb *TaskBox) calculate() {
+	selected := -1
+	if (tb.sorted || tb.mode == modeAgenda || tb.mode == modeCalendar) &&
+		tb.cursor < len(tb.view) {
+		selected = tb.view[tb.cursor]
+	}
+	tb.openIDs = tb.openTaskIDs()
+	if tb.mode == modeAgenda {
+		tb.agendaView()
+		tb.followLine(selected)
+		tb.skipHeader(1)
+		return
+	}
+	if tb.mode == modeCalendar {
+		tb.calendarView()
+		tb.followLine(selected)
+		return
+	}
 	tb.view = make([]int, 0)
+	notes := tb.noteLines()
+	owner := false // is task of notes visible
 	for i, line := range tb.Lines {
-		if tb.inFilter(line) {
+		if notes[i] {
+			if tb.showNotes && owner {
+				tb.view = append(tb.view, i)
+			}
+			continue
+		}
+		owner = tb.inFilter(line)
+		if owner {
 			tb.view = append(tb.view, i)
 		}
 	}
+	if tb.sorted && tb.mode != modeArchive {
+		tb.sortView()
+	}
+	tb.followLine(selected)
+}
+
+// Keep cursor on the line with index after view is rebuilt
+func (tb *TaskBox) followLine(index int) {
+	for pos, i := range tb.view {
+		if i == index && index >= 0 {
+			tb.cursor = pos
+		}
+	}
 	if len(tb.view) == 0 {
 		tb.cursor = 0
 	} else if tb.cursor >= len(tb.view) {
@@ -86,14 +168,54 @@ func (tb *TaskBox) calculate() {
 	}
 }
 
+// Order tasks by priority and due date. Other lines are hidden
+func (tb *TaskBox) sortView() {
+	tasks := make([]int, 0, len(tb.view))
+	parsed := make(map[int]Task)
+	for _, i := range tb.view {
+		if lineTypeOf(tb.Lines[i]) == lineTask {
+			tasks = append(tasks, i)
+			parsed[i] = ParseTask(tb.Lines[i])
+		}
+	}
+	sort.SliceStable(tasks, func(a, b int) bool {
+		return taskLess(parsed[tasks[a]], parsed[tasks[b]])
+	})
+	tb.view = tasks
+}
+
+func taskLess(a, b Task) bool {
+	if a.Priority != b.Priority {
+		return a.Priority > b.Priority
+	}
+	if a.Due.IsZero() != b.Due.IsZero() {
+		return !a.Due.IsZero()
+	}
+	return a.Due.Before(b.Due)
+}
+
+func (tb *TaskBox) ToggleSortedView() {
+	tb.sorted = !tb.sorted
+	tb.calculate()
+	tb.scrollToCursor()
+}
+
 func (tb *TaskBox) inFilter(s string) bool {
 	switch lineTypeOf(s) {
 	case lineComment:
 		return tb.mode == modeArchive
 	case lineTask:
 		t := ParseTask(s)
-		return tb.mode != modeArchive &&
-			(tb.filter == StatusAll || tb.filter == t.Status)
+		if tb.mode == modeArchive {
+			return false
+		}
+		if tb.tag != "" && !t.HasTag(tb.tag) {
+			return false
+		}
+		if tb.filter == StatusActionable {
+			return t.Status == StatusOpen && len(tb.openBlockers(t)) == 0
+		}
+		return tb.filter == StatusAll || tb.filter == t.Status
 	case lineNormal:
 		return tb.mode != modeArchive
 	}
@@ -107,8 +229,16 @@ func (tb *TaskBox) Filter(s Status) {
 	tb.calculate()
 }
 
+// Show only tasks with tag. Empty tag shows all tasks
+func (tb *TaskBox) FilterTag(tag string) {
+	tb.cursor = 0
+	tb.scroll = 0
+	tb.tag = tag
+	tb.calculate()
+}
+
 func (tb *TaskBox) NextFilter() {
-	filters := [3]Status{StatusOpen, StatusClosed, StatusAll}
+	filters := [4]Status{StatusOpen, StatusActionable, StatusClosed, StatusAll}
 	for i, f := range filters {
 		if tb.filter == Status(f) {
 			i++
@@ -130,51 +260,100 @@ func (tb TaskBox) TaskFilterPrefix() string {
 	return string(s)
 }
 
-func (tb *TaskBox) String() string {
-	if len(tb.view) == 0 {
-		if tb.mode == modeArchive {
-			return "> No tasks in Archive. Press Esc to return to Task mode\n"
-		} else {
-			return "> No tasks. Press Enter to create one\n"
-		}
+func (tb *TaskBox) emptyMessage() string {
+	switch tb.mode {
+	case modeArchive:
+		return "> No tasks in Archive. Press Esc to return to Task mode"
+	case modeAgenda:
+		return "> No tasks in Agenda. Press Esc to return to Task mode"
+	case modeCalendar:
+		return "> No tasks due on " + tb.day.Format(DateFormat)
 	}
-	var to int
-	if tb.scroll+tb.h > len(tb.view) {
-		to = len(tb.view)
-	} else {
-		to = tb.scroll + tb.h
+	return "> No tasks. Press Enter to create one"
+}
+
+// Visible lines indexes. Rows of last wrapped line may not fit
+func (tb *TaskBox) page() []int {
+	to := tb.scroll
+	for rows := 0; to < len(tb.view) && rows < tb.h; to++ {
+		rows += tb.lineHeight(to)
+	}
+	return tb.view[tb.scroll:to]
+}
+
+// Text of view line without cursor mark
+func (tb *TaskBox) lineText(index int) string {
+	if index < 0 {
+		return agendaGroups[-index-1] + ":"
 	}
+	if tb.mode == modeArchive {
+		return ParseComment(tb.Lines[index])
+	}
+	return tb.Lines[index]
+}
 
-	var s strings.Builder
-	var l string
+// Line as it is shown on the screen, one string per wrapped row
+func (tb *TaskBox) displayRows(i int, index int) []string {
 	var cursor rune
-	for i, index := range tb.view[tb.scroll:to] {
-		if i == tb.CursorToPage() {
-			cursor = '>'
+	switch {
+	case i == tb.CursorToPage():
+		cursor = '>'
+	case tb.HasSelection() && tb.isSelectedAt(tb.scroll+i):
+		cursor = '*'
+	default:
+		cursor = ' '
+	}
+	rows := tb.wrap(tb.lineText(index))
+	for r := range rows {
+		if r == 0 {
+			rows[r] = fmt.Sprintf("%c %s", cursor, rows[r])
 		} else {
-			cursor = ' '
+			rows[r] = "  " + rows[r]
 		}
-		l = tb.Lines[index]
-		if tb.mode == modeArchive {
-			l = ParseComment(l)
+	}
+	return rows
+}
+
+func (tb *TaskBox) String() string {
+	if len(tb.view) == 0 {
+		return tb.emptyMessage() + "\n"
+	}
+	var s strings.Builder
+	n := 0
+	for i, index := range tb.page() {
+		for _, row := range tb.displayRows(i, index) {
+			if n < tb.h {
+				s.WriteString(row)
+				s.WriteRune('\n')
+			}
+			n++
 		}
-		fmt.Fprintf(&s, "%c %s\n", cursor, l)
 	}
 	return s.String()
 }
 
 func (tb *TaskBox) scrollToCursor() {
-	if tb.cursor-tb.scroll >= tb.h {
-		tb.scroll = tb.cursor - tb.h + 1
-	}
 	if tb.cursor < tb.scroll {
 		tb.scroll = tb.cursor
 	}
+	// Scroll down until all rows of cursor line fit
+	rows := 0
+	for top := tb.cursor; top >= tb.scroll; top-- {
+		rows += tb.lineHeight(top)
+		if rows > tb.h {
+			if top < tb.cursor {
+				top++
+			}
+			tb.scroll = top
+			return
+		}
+	}
 }
 
 func (tb *TaskBox) CursorDown() {
 	if tb.cursor < len(tb.view)-1 {
 		tb.cursor++
+		tb.skipHeader(1)
 		tb.scrollToCursor()
 	}
 }
@@ -182,161 +361,219 @@ func (tb *TaskBox) CursorDown() {
 func (tb *TaskBox) CursorUp() {
 	if tb.cursor > 0 {
 		tb.cursor--
+		tb.skipHeader(-1)
 		tb.scrollToCursor()
 	}
 }
 
 func (tb *TaskBox) PageDown() {
-	tb.cursor = tb.cursor + tb.h - 1
-	if tb.cursor >= len(tb.view) {
-		tb.cursor = len(tb.view) - 1
+	for n := 0; tb.cursor < len(tb.view)-1 && n < tb.h-1; tb.cursor++ {
+		n += tb.lineHeight(tb.cursor)
 	}
 	tb.scrollToCursor()
 }
 
 func (tb *TaskBox) PageUp() {
-	tb.cursor = tb.cursor - tb.h + 1
-	if tb.cursor < 0 {
-		tb.cursor = 0
+	for n := 0; tb.cursor > 0 && n < tb.h-1; tb.cursor-- {
+		n += tb.lineHeight(tb.cursor)
 	}
 	tb.scrollToCursor()
 }
 
+// Move cursor to line if it is visible
+func (tb *TaskBox) CursorToLine(index int) {
+	for pos, i := range tb.view {
+		if i == index {
+			tb.cursor = pos
+			tb.scrollToCursor()
+			return
+		}
+	}
+}
+
 func (tb *TaskBox) CursorToPage() int {
 	return tb.cursor - tb.scroll
 }
 
 func (tb *TaskBox) CursorToY() int {
-	return tb.y + tb.CursorToPage()
+	return tb.y + tb.rowsBetween(tb.scroll, tb.cursor)
 }
 
 func (tb *TaskBox) SelectedLine() (int, string) {
-	if len(tb.view) > 0 {
+	if len(tb.view) > 0 && tb.view[tb.cursor] >= 0 {
 		index := tb.view[tb.cursor]
 		return index, tb.Lines[index]
 	} else {
+		// No lines or agenda group header
 		return -1, ""
 	}
 }
 
 func (tb *TaskBox) HandleTaskEvent(ev termbox.Event) {
-	switch {
-	case ev.Key == termbox.KeyEnter || ev.Key == termbox.KeyEnd || ev.Ch == 'a':
-		tb.EnterEditMode()
-	case ev.Key == termbox.KeyInsert || ev.Ch == 'i':
-		tb.InsertLineAndEdit()
-	case ev.Key == termbox.KeyDelete || ev.Ch == 'd':
-		tb.TaskDeleteKey()
-	case ev.Key == termbox.KeyArrowDown || ev.Ch == 'j':
-		tb.CursorDown()
-	case ev.Key == termbox.KeyArrowUp || ev.Ch == 'k':
-		tb.CursorUp()
-	case ev.Key == termbox.KeyPgdn:
-		tb.PageDown()
-	case ev.Key == termbox.KeyPgup:
-		tb.PageUp()
-	case ev.Key == termbox.KeySpace:
-		tb.ToggleTask()
-	case ev.Ch == 'u':
-		tb.undo.Undo()
-	case ev.Ch == 'r':
-		tb.undo.Redo()
-	case ev.Key == termbox.KeyArrowLeft || ev.Ch == 'h':
-		tb.MoveLineUp()
-	case ev.Key == termbox.KeyArrowRight || ev.Ch == 'l':
-		tb.MoveLineDown()
-	case ev.Ch == 'c':
-		tb.CopyLine()
-	case ev.Key == termbox.KeyCtrlL:
-		tb.MoveLineToBottom()
-	case ev.Ch == 'f':
-		tb.NextFilter()
-	case ev.Key == termbox.KeyCtrlS || ev.Ch == 's' || ev.Ch == 'w':
-		tb.Save(tb.path)
-	case ev.Ch == 'z':
-		tb.ToggleComment()
-	case ev.Key == termbox.KeyCtrlF:
-		tb.EnterArchiveMode()
-	case ev.Ch == '?':
-		help()
-	case ev.Key == termbox.KeyCtrlQ ||
-		ev.Key == termbox.KeyCtrlX ||
-		ev.Key == termbox.KeyCtrlC ||
-		ev.Ch == 'q':
-		tb.mode = modeExit
+	tb.HandleKeySequence(ev)
+}
+
+// Show error in status line
+func (tb *TaskBox) showError(err error) {
+	if err != nil {
+		tb.message = err.Error()
 	}
 }
 
 func (tb *TaskBox) TaskDeleteKey() {
-	i, _ := tb.SelectedLine()
-	if i < 0 {
-		return
+	sel := tb.withNotes(tb.Selection())
+	for k := len(sel) - 1; k >= 0; k-- {
+		tb.removeLine(sel[k])
 	}
-	tb.DeleteLine(i)
+	tb.ClearSelection()
 	tb.calculate()
 }
 
 func (tb *TaskBox) ToggleTask() {
-	i, s := tb.SelectedLine()
-	if lineTypeOf(s) == lineTask {
+	tb.openIDs = tb.openTaskIDs()
+	for _, i := range tb.Selection() {
+		s := tb.Lines[i]
+		if lineTypeOf(s) != lineTask {
+			continue
+		}
 		task := ParseTask(s)
+		event := EventReopened
 		if task.Status == StatusOpen {
 			task.Status = StatusClosed
+			event = EventCompleted
+			if blockers := tb.openBlockers(task); len(blockers) > 0 {
+				tb.message = blockedMessage(task, blockers)
+			}
 		} else {
 			task.Status = StatusOpen
 		}
 		tb.UpdateLine(i, task.String())
-		tb.calculate()
+		tb.logEvent(event, task.String())
 	}
+	tb.ClearSelection()
+	tb.calculate()
 }
 
 func (tb *TaskBox) ToggleComment() {
-	i, s := tb.SelectedLine()
-	if i < 0 {
-		return
+	for _, i := range tb.withNotes(tb.Selection()) {
+		s := tb.Lines[i]
+		event := EventArchived
+		if lineTypeOf(s) == lineComment {
+			s = ParseComment(s)
+			event = EventUnarchived
+		} else {
+			s = MakeComment(s)
+		}
+		tb.UpdateLine(i, s)
+		tb.logEvent(event, s)
 	}
-	if lineTypeOf(s) == lineComment {
-		s = ParseComment(s)
-	} else {
-		s = MakeComment(s)
+	tb.ClearSelection()
+	tb.calculate()
+}
+
+// Archive closed tasks of the file
+func (tb *TaskBox) ArchiveClosed() {
+	var closed []int
+	for i, s := range tb.Lines {
+		if lineTypeOf(s) == lineTask && ParseTask(s).Status == StatusClosed {
+			closed = append(closed, i)
+		}
+	}
+	for _, i := range tb.withNotes(closed) {
+		s := MakeComment(tb.Lines[i])
+		tb.UpdateLine(i, s)
+		tb.logEvent(EventArchived, s)
+	}
+	tb.ClearSelection()
+	tb.calculate()
+	tb.message = fmt.Sprintf("%d tasks archived", len(closed))
+}
+
+// Change priority of selected tasks
+func (tb *TaskBox) ChangePriority(delta int) {
+	for _, i := range tb.Selection() {
+		if lineTypeOf(tb.Lines[i]) == lineTask {
+			task := ParseTask(tb.Lines[i])
+			task.SetPriority(task.Priority + delta)
+			tb.UpdateLine(i, task.String())
+		}
 	}
-	tb.UpdateLine(i, s)
 	tb.calculate()
 }
 
+// Move selected lines one position down in the view
 func (tb *TaskBox) MoveLineDown() {
-	if tb.cursor >= len(tb.view)-1 {
+	if tb.sorted {
 		return
 	}
-	index1 := tb.view[tb.cursor]
-	index2 := tb.view[tb.cursor+1]
-	tb.SwapLines(index1, index2)
+	positions := tb.selectedPositions()
+	if len(positions) == 0 || positions[len(positions)-1] >= len(tb.view)-1 {
+		return
+	}
+	cursorSelected := tb.isSelectedAt(tb.cursor) || !tb.HasSelection()
+	for k := len(positions) - 1; k >= 0; k-- {
+		pos := positions[k]
+		tb.swapBlocks(tb.view[pos], tb.view[pos+1])
+		tb.calculate()
+	}
 	tb.calculate()
-	tb.CursorDown()
+	if cursorSelected {
+		tb.CursorDown()
+	}
 }
 
+// Move selected lines one position up in the view
 func (tb *TaskBox) MoveLineUp() {
-	if tb.cursor <= 0 {
+	if tb.sorted {
+		return
+	}
+	positions := tb.selectedPositions()
+	if len(positions) == 0 || positions[0] <= 0 {
 		return
 	}
-	index1 := tb.view[tb.cursor]
-	index2 := tb.view[tb.cursor-1]
-	tb.SwapLines(index1, index2)
+	cursorSelected := tb.isSelectedAt(tb.cursor) || !tb.HasSelection()
+	for _, pos := range positions {
+		tb.swapBlocks(tb.view[pos-1], tb.view[pos])
+		tb.calculate()
+	}
 	tb.calculate()
-	tb.CursorUp()
+	if cursorSelected {
+		tb.CursorUp()
+	}
 }
 
 func (tb *TaskBox) MoveLineToBottom() {
-	if tb.cursor >= len(tb.view)-1 {
+	if tb.sorted {
+		return
+	}
+	if !tb.HasSelection() && tb.cursor >= len(tb.view)-1 {
 		return
 	}
-	i, _ := tb.SelectedLine()
-	tb.MakeLastLine(i)
+	for k, i := range tb.withNotes(tb.Selection()) {
+		// Every moved line shifts the rest up
+		tb.MakeLastLine(i - k)
+	}
+	tb.ClearSelection()
 	tb.calculate()
 }
 
+// Insert copy of selected lines above them. Marked lines which are
+// apart get their copies above each of them
 func (tb *TaskBox) CopyLine() {
-	i, s := tb.SelectedLine()
-	tb.InsertLine(i, s)
+	sel := tb.withNotes(tb.Selection())
+	// Runs of adjacent lines from the bottom so indexes above stay
+	for end := len(sel); end > 0; {
+		start := end - 1
+		for start > 0 && sel[start-1] == sel[start]-1 {
+			start--
+		}
+		from := sel[start]
+		for k := 0; k < end-start; k++ {
+			tb.InsertLine(from+k, tb.Lines[from+2*k])
+			tb.renewID(from + k)
+		}
+		end = start
+	}
+	tb.ClearSelection()
 	tb.calculate()
 }
@@ -322,3 +322,44 @@ func TestToggleAndFilterOut(t *testing.T) {
 	`))
 
 }
+
+func TestSortedView(t *testing.T) {
+	tb := &TaskBox{Lines: []string{
+		"# Heading",
+		"- [ ] foo",
+		"- [ ] bar due:2026-02-01",
+		"- [ ] baz !!",
+		"- [ ] qux due:2026-01-01",
+	}}
+	tb.calculate()
+	tb.h = 5
+	tb.ToggleSortedView()
+	assert.Equal(t, heredoc.Doc(`
+		> - [ ] baz !!
+		  - [ ] qux due:2026-01-01
+		  - [ ] bar due:2026-02-01
+		  - [ ] foo
+	`), tb.String())
+
+	// Underlying lines are not reordered
+	assert.Equal(t, "- [ ] foo", tb.Lines[1])
+
+	tb.CursorToLine(1)
+	tb.ChangePriority(1)
+	assert.Equal(t, "- [ ] foo !", tb.Lines[1])
+	assert.Equal(t, heredoc.Doc(`
+		  - [ ] baz !!
+		> - [ ] foo !
+		  - [ ] qux due:2026-01-01
+		  - [ ] bar due:2026-02-01
+	`), tb.String())
+
+	tb.MoveLineUp()
+	assert.Equal(t, "- [ ] foo !", tb.Lines[1])
+
+	tb.ChangePriority(-1)
+	assert.Equal(t, "- [ ] foo", tb.Lines[1])
+
+	tb.ToggleSortedView()
+	assert.Equal(t, 5, len(tb.view))
+}
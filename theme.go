@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"github.com/nsf/termbox-go"
+	"sort"
+	"strings"
+)
+
+type Style struct {
+	Fg, Bg termbox.Attribute
+}
+
+type Theme struct {
+	Name     string
+	Output   termbox.OutputMode
+	Normal   Style
+	Heading  Style
+	Open     Style
+	Low      Style // open tasks by priority
+	Medium   Style
+	High     Style
+	Blocked  Style // open tasks waiting for other tasks
+	Closed   Style
+	Archived Style
+	Cursor   Style
+	Selected Style
+	Status   Style
+	Editor   Style
+}
+
+// Color from 256 color palette (Output256 mode)
+func c256(n int) termbox.Attribute {
+	return termbox.Attribute(n + 1)
+}
+
+var themes = map[string]*Theme{
+	"dark": {
+		Name:     "dark",
+		Output:   termbox.OutputNormal,
+		Normal:   Style{termbox.ColorDefault, termbox.ColorDefault},
+		Heading:  Style{termbox.ColorYellow | termbox.AttrBold, termbox.ColorDefault},
+		Open:     Style{termbox.ColorDefault, termbox.ColorDefault},
+		Closed:   Style{termbox.ColorGreen, termbox.ColorDefault},
+		Archived: Style{termbox.ColorBlue, termbox.ColorDefault},
+		Low:      Style{termbox.ColorCyan, termbox.ColorDefault},
+		Medium:   Style{termbox.ColorMagenta, termbox.ColorDefault},
+		High:     Style{termbox.ColorRed | termbox.AttrBold, termbox.ColorDefault},
+		Blocked:  Style{termbox.ColorBlack | termbox.AttrBold, termbox.ColorDefault},
+		Cursor:   Style{termbox.ColorWhite | termbox.AttrBold, termbox.ColorBlue},
+		Selected: Style{termbox.ColorWhite, termbox.ColorMagenta},
+		Status:   Style{termbox.ColorBlack, termbox.ColorCyan},
+		Editor:   Style{termbox.ColorWhite | termbox.AttrBold, termbox.ColorBlack},
+	},
+	"light": {
+		Name:     "light",
+		Output:   termbox.OutputNormal,
+		Normal:   Style{termbox.ColorBlack, termbox.ColorWhite},
+		Heading:  Style{termbox.ColorBlue | termbox.AttrBold, termbox.ColorWhite},
+		Open:     Style{termbox.ColorBlack, termbox.ColorWhite},
+		Closed:   Style{termbox.ColorGreen, termbox.ColorWhite},
+		Archived: Style{termbox.ColorMagenta, termbox.ColorWhite},
+		Low:      Style{termbox.ColorCyan, termbox.ColorWhite},
+		Medium:   Style{termbox.ColorMagenta, termbox.ColorWhite},
+		High:     Style{termbox.ColorRed | termbox.AttrBold, termbox.ColorWhite},
+		Blocked:  Style{termbox.ColorYellow, termbox.ColorWhite},
+		Cursor:   Style{termbox.ColorBlack | termbox.AttrBold, termbox.ColorCyan},
+		Selected: Style{termbox.ColorBlack, termbox.ColorYellow},
+		Status:   Style{termbox.ColorWhite, termbox.ColorBlue},
+		Editor:   Style{termbox.ColorBlack, termbox.ColorYellow},
+	},
+	"monochrome": {
+		Name:     "monochrome",
+		Output:   termbox.OutputNormal,
+		Heading:  Style{termbox.AttrBold, 0},
+		High:     Style{termbox.AttrBold, 0},
+		Cursor:   Style{termbox.AttrReverse, 0},
+		Selected: Style{termbox.AttrBold | termbox.AttrUnderline, 0},
+		Status:   Style{termbox.AttrBold, 0},
+		Editor:   Style{termbox.AttrUnderline, 0},
+		Archived: Style{termbox.AttrUnderline, 0},
+	},
+	"256": {
+		Name:     "256",
+		Output:   termbox.Output256,
+		Normal:   Style{c256(252), c256(235)},
+		Heading:  Style{c256(214) | termbox.AttrBold, c256(235)},
+		Open:     Style{c256(252), c256(235)},
+		Closed:   Style{c256(244), c256(235)},
+		Archived: Style{c256(103), c256(235)},
+		Low:      Style{c256(116), c256(235)},
+		Medium:   Style{c256(176), c256(235)},
+		High:     Style{c256(203) | termbox.AttrBold, c256(235)},
+		Blocked:  Style{c256(241), c256(235)},
+		Cursor:   Style{c256(231) | termbox.AttrBold, c256(24)},
+		Selected: Style{c256(231), c256(96)},
+		Status:   Style{c256(235), c256(109)},
+		Editor:   Style{c256(231), c256(238)},
+	},
+}
+
+var theme = themes["dark"]
+
+func themeNames() string {
+	names := make([]string, 0, len(themes))
+	for name := range themes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
+/*
+Theme given by flag wins over NO_COLOR environment variable
+which wins over theme from config file
+*/
+func ChooseTheme(flagTheme, configTheme string, noColor bool) (*Theme, error) {
+	name := "dark"
+	switch {
+	case flagTheme != "":
+		name = flagTheme
+	case noColor:
+		name = "monochrome"
+	case configTheme != "":
+		name = configTheme
+	}
+	t, ok := themes[name]
+	if !ok {
+		return nil, fmt.Errorf("Unknown theme: %s (%s)", name, themeNames())
+	}
+	return t, nil
+}
+
+func isHeading(s string) bool {
+	return strings.HasPrefix(s, "#")
+}
+
+func (t *Theme) LineStyle(s string) Style {
+	switch lineTypeOf(s) {
+	case lineTask:
+		task := ParseTask(s)
+		switch {
+		case task.Status == StatusClosed:
+			return t.Closed
+		case task.Priority >= 3:
+			return t.High
+		case task.Priority == 2:
+			return t.Medium
+		case task.Priority == 1:
+			return t.Low
+		}
+		return t.Open
+	case lineComment:
+		return t.Archived
+	}
+	if isHeading(s) {
+		return t.Heading
+	}
+	return t.Normal
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestChooseTheme(t *testing.T) {
+	var cases = []struct {
+		flag, config string
+		noColor      bool
+		theme        string
+	}{
+		{"", "", false, "dark"},
+		{"", "light", false, "light"},
+		{"", "light", true, "monochrome"},
+		{"256", "light", true, "256"},
+		{"light", "", false, "light"},
+	}
+	for _, c := range cases {
+		th, err := ChooseTheme(c.flag, c.config, c.noColor)
+		assert.Nil(t, err)
+		assert.Equal(t, c.theme, th.Name)
+	}
+
+	_, err := ChooseTheme("", "foo", false)
+	assert.EqualError(t, err, "Unknown theme: foo (256,dark,light,monochrome)")
+}
+
+func TestLineStyle(t *testing.T) {
+	th := themes["light"]
+	assert.Equal(t, th.Open, th.LineStyle("- [ ] Foo"))
+	assert.Equal(t, th.Closed, th.LineStyle("- [x] Foo"))
+	assert.Equal(t, th.Heading, th.LineStyle("## Foo"))
+	assert.Equal(t, th.Archived, th.LineStyle("<!-- - [ ] Foo -->"))
+	assert.Equal(t, th.Normal, th.LineStyle("Foo"))
+	assert.Equal(t, th.Normal, th.LineStyle(""))
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+/*
+Time spent on tasks is kept in sidecar log next to task file
+(TODO.md.time for TODO.md). Each interval is one CSV record:
+
+	start,end,key,description
+
+Key identifies the task. See taskKey
+*/
+
+const timeLogExt = ".time"
+
+type TimeEntry struct {
+	Start, End  time.Time
+	Key         string
+	Description string
+}
+
+func (e TimeEntry) Duration() time.Duration {
+	return e.End.Sub(e.Start)
+}
+
+// Running timer
+type Timer struct {
+	TimeEntry
+	path string // task file
+}
+
+// Set while timer runs. Read by tickTimer goroutine
+var timerRunning int32
+
+// Path of sidecar file for task file or workspace directory
+func sidecarPath(path, ext string) string {
+	if isDir(path) {
+		return filepath.Join(path, ".taskbox"+ext)
+	}
+	return path + ext
+}
+
+/*
+Key of task in sidecar logs. Tasks without id are keyed by description
+without priority and due date, so they keep history when they are
+reprioritized or rescheduled
+*/
+func taskKey(task Task) string {
+	if task.ID != "" {
+		return "id:" + task.ID
+	}
+	task.SetPriority(0)
+	task.SetDue(time.Time{})
+	return strings.TrimSpace(task.Description)
+}
+
+func AppendTimeLog(path string, e TimeEntry) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := csv.NewWriter(f)
+	w.Write([]string{
+		e.Start.Format(time.RFC3339), e.End.Format(time.RFC3339),
+		e.Key, e.Description,
+	})
+	w.Flush()
+	return w.Error()
+}
+
+// Read time log. Missing file is ok
+func LoadTimeLog(path string) ([]TimeEntry, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = 4
+	var entries []TimeEntry
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		var e TimeEntry
+		if e.Start, err = time.Parse(time.RFC3339, rec[0]); err != nil {
+			return nil, err
+		}
+		if e.End, err = time.Parse(time.RFC3339, rec[1]); err != nil {
+			return nil, err
+		}
+		e.Key, e.Description = rec[2], rec[3]
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// Start timer for task under cursor. Running timer is stopped
+func (tb *TaskBox) StartTimer() error {
+	_, s := tb.SelectedLine()
+	if lineTypeOf(s) != lineTask {
+		return fmt.Errorf("Not a task")
+	}
+	if err := tb.StopTimer(); err != nil {
+		return err
+	}
+	task := ParseTask(s)
+	tb.timer = &Timer{
+		TimeEntry: TimeEntry{
+			Start:       time.Now(),
+			Key:         taskKey(task),
+			Description: task.Description,
+		},
+		path: tb.path,
+	}
+	atomic.StoreInt32(&timerRunning, 1)
+	return nil
+}
+
+// Stop timer and write time interval to log
+func (tb *TaskBox) StopTimer() error {
+	if tb.timer == nil {
+		return nil
+	}
+	e := tb.timer.TimeEntry
+	e.End = time.Now()
+	path := tb.timer.path
+	tb.timer = nil
+	atomic.StoreInt32(&timerRunning, 0)
+	return AppendTimeLog(sidecarPath(path, timeLogExt), e)
+}
+
+func (tb *TaskBox) ToggleTimer() error {
+	if tb.timer != nil {
+		return tb.StopTimer()
+	}
+	return tb.StartTimer()
+}
+
+func formatDuration(d time.Duration) string {
+	d = d.Round(time.Second)
+	return fmt.Sprintf("%d:%02d:%02d",
+		int(d.Hours()), int(d.Minutes())%60, int(d.Seconds())%60)
+}
+
+/*
+Total time per task, tag or day. Rows are sorted by name,
+tasks are named by their latest description, days are taken
+from interval start
+*/
+func TimeReport(entries []TimeEntry, by string) ([][]string, error) {
+	totals := make(map[string]time.Duration)
+	tasks := make(map[string]string)
+	for _, e := range entries {
+		switch by {
+		case "task":
+			totals[e.Key] += e.Duration()
+			tasks[e.Key] = e.Description
+		case "tag":
+			task := ParseTask(TaskPrefix + e.Description)
+			for _, tag := range task.Tags {
+				totals["#"+tag] += e.Duration()
+			}
+		case "day":
+			totals[e.Start.Local().Format(DateFormat)] += e.Duration()
+		default:
+			return nil, fmt.Errorf("Unknown report: %s (task,tag,day)", by)
+		}
+	}
+	rows := [][]string{}
+	for key, total := range totals {
+		name := key
+		if by == "task" {
+			name = tasks[key]
+		}
+		rows = append(rows, []string{name, formatDuration(total)})
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i][0] < rows[j][0] })
+	return append([][]string{{by, "time"}}, rows...), nil
+}
+
+func WriteCSV(path string, rows [][]string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := csv.NewWriter(f)
+	w.WriteAll(rows)
+	return w.Error()
+}
+
+/*
+Show report of time logged for current file or save it to CSV file:
+
+	time-report [task|tag|day] [file.csv]
+*/
+func (tb *TaskBox) ShowTimeReport(args []string) error {
+	by := "task"
+	if len(args) > 0 {
+		by = args[0]
+	}
+	if len(args) > 2 {
+		return fmt.Errorf("Too many arguments")
+	}
+	entries, err := LoadTimeLog(sidecarPath(tb.path, timeLogExt))
+	if err != nil {
+		return err
+	}
+	rows, err := TimeReport(entries, by)
+	if err != nil {
+		return err
+	}
+	if len(args) == 2 {
+		if err := WriteCSV(args[1], rows); err != nil {
+			return err
+		}
+		tb.message = fmt.Sprintf("Report saved to %s", args[1])
+		return nil
+	}
+	items := make([]string, len(rows)-1)
+	for i, row := range rows[1:] {
+		items[i] = fmt.Sprintf("%10s  %s", row[1], row[0])
+	}
+	tb.recordSteps()
+	chooseItem("Time per "+by, items, 0)
+	return nil
+}
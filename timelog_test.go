@@ -0,0 +1,92 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTimer(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "timelog")
+	defer os.RemoveAll(dir)
+	tb := &TaskBox{Lines: []string{"# foo", "- [ ] bar #work"}}
+	tb.path = filepath.Join(dir, "TODO.md")
+	tb.calculate()
+
+	assert.EqualError(t, tb.StartTimer(), "Not a task")
+	tb.CursorDown()
+	assert.Nil(t, tb.ToggleTimer())
+	assert.Equal(t, "bar #work", tb.timer.Key)
+	assert.Nil(t, tb.ToggleTimer())
+	assert.Nil(t, tb.timer)
+
+	entries, err := LoadTimeLog(filepath.Join(dir, "TODO.md.time"))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(entries))
+	assert.Equal(t, "bar #work", entries[0].Description)
+	assert.True(t, entries[0].Duration() >= 0)
+
+	entries, err = LoadTimeLog(filepath.Join(dir, "missing.time"))
+	assert.Nil(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestTimeReport(t *testing.T) {
+	day := time.Date(2026, 1, 1, 10, 0, 0, 0, time.Local)
+	entries := []TimeEntry{
+		{day, day.Add(time.Hour), "foo #a #b", "foo #a #b"},
+		{day.Add(2 * time.Hour), day.Add(150 * time.Minute), "bar #a", "bar #a"},
+		{day.Add(24 * time.Hour), day.Add(24*time.Hour + 90*time.Second),
+			"foo #a #b", "foo #a #b"},
+	}
+
+	rows, err := TimeReport(entries, "task")
+	assert.Nil(t, err)
+	assert.Equal(t, [][]string{
+		{"task", "time"},
+		{"bar #a", "0:30:00"},
+		{"foo #a #b", "1:01:30"},
+	}, rows)
+
+	rows, _ = TimeReport(entries, "tag")
+	assert.Equal(t, [][]string{
+		{"tag", "time"},
+		{"#a", "1:31:30"},
+		{"#b", "1:01:30"},
+	}, rows)
+
+	rows, _ = TimeReport(entries, "day")
+	assert.Equal(t, [][]string{
+		{"day", "time"},
+		{"2026-01-01", "1:30:00"},
+		{"2026-01-02", "0:01:30"},
+	}, rows)
+
+	_, err = TimeReport(entries, "foo")
+	assert.EqualError(t, err, "Unknown report: foo (task,tag,day)")
+}
+
+func TestTimeReportByID(t *testing.T) {
+	day := time.Date(2026, 1, 1, 10, 0, 0, 0, time.Local)
+	entries := []TimeEntry{
+		{day, day.Add(time.Hour), "id:abc", "foo id:abc"},
+		{day, day.Add(time.Hour), "id:abc", "bar id:abc"},
+	}
+	rows, _ := TimeReport(entries, "task")
+	assert.Equal(t, [][]string{
+		{"task", "time"},
+		{"bar id:abc", "2:00:00"},
+	}, rows)
+}
+
+func TestTaskKey(t *testing.T) {
+	key := func(s string) string { return taskKey(ParseTask(s)) }
+	assert.Equal(t, "foo #bar", key("- [ ] foo #bar"))
+	assert.Equal(t, "foo #bar", key("- [x] foo !! #bar due:2026-01-02"))
+	assert.Equal(t, "foo", key("- [ ] (A) foo"))
+	assert.Equal(t, "foo", key("- [ ] foo p:1"))
+	assert.Equal(t, "id:abc", key("- [ ] foo ! id:abc"))
+}
@@ -4,10 +4,16 @@ import (
 	"reflect"
 )
 
+// State of all open buffers. See buffers.go
 type UndoState struct {
-	lines  []string
+	lines  [][]string
+	buffer int
 	cursor int
 	filter Status
+	// History events leading to state and opposite events made
+	// on undo. See history.go
+	events []*pendingEvent
+	undone []*pendingEvent
 }
 
 type Undo struct {
@@ -23,9 +29,14 @@ func NewUndo(tb *TaskBox) *Undo {
 }
 
 func (u *Undo) GetState() UndoState {
-	lines := make([]string, len(u.tb.Lines))
-	copy(lines, u.tb.Lines)
+	all := u.tb.allLines()
+	lines := make([][]string, len(all))
+	for i := range all {
+		lines[i] = make([]string, len(all[i]))
+		copy(lines[i], all[i])
+	}
 	return UndoState{
+		buffer: u.tb.current,
 		cursor: u.tb.cursor,
 		filter: u.tb.filter,
 		lines:  lines,
@@ -36,22 +47,69 @@ func (u *Undo) CurrentState() UndoState {
 	return u.history[u.stateIndex]
 }
 
-func (u *Undo) RestoreState() {
+// Mark buffers changed between states as modified
+func (u *Undo) markModified(from, to UndoState) {
+	for i := range to.lines {
+		if i >= len(from.lines) || !reflect.DeepEqual(from.lines[i], to.lines[i]) {
+			u.tb.setModified(i, true)
+		}
+	}
+}
+
+func (u *Undo) RestoreState(from UndoState) {
 	state := u.CurrentState()
+	if len(u.tb.buffers) > 0 {
+		u.tb.stash()
+		for i, b := range u.tb.buffers {
+			b.Lines = make([]string, len(state.lines[i]))
+			copy(b.Lines, state.lines[i])
+		}
+		u.tb.current = state.buffer
+		u.tb.unstash()
+	} else {
+		u.tb.Lines = make([]string, len(state.lines[0]))
+		copy(u.tb.Lines, state.lines[0])
+	}
 	u.tb.cursor = state.cursor
 	u.tb.filter = state.filter
-	u.tb.Lines = make([]string, len(state.lines))
-	copy(u.tb.Lines, state.lines)
-	u.tb.modified = true
+	u.tb.ClearSelection()
+	u.markModified(from, state)
 }
 
+// Nil and empty lists are the same
+func sameLines(a, b [][]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if len(a[i]) != len(b[i]) {
+			return false
+		}
+		for j := range a[i] {
+			if a[i][j] != b[i][j] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Save state if lines are changed. Lines are copied only then
 func (u *Undo) PutState() {
-	if u.stateIndex >= 0 && reflect.DeepEqual(u.CurrentState().lines, u.tb.Lines) {
+	events := u.tb.stateEvents
+	u.tb.stateEvents = nil
+	if u.stateIndex >= 0 && sameLines(u.CurrentState().lines, u.tb.allLines()) {
+		current := &u.history[u.stateIndex]
+		current.events = append(current.events, events...)
 		return
 	}
-	u.tb.modified = true
+	state := u.GetState()
+	state.events = events
+	if u.stateIndex >= 0 {
+		u.markModified(u.CurrentState(), state)
+	}
 	u.history = u.history[:u.stateIndex+1]
-	u.history = append(u.history, u.GetState())
+	u.history = append(u.history, state)
 	u.stateIndex++
 }
 
@@ -59,14 +117,19 @@ func (u *Undo) Undo() {
 	if u.stateIndex == 0 {
 		return
 	}
+	from := u.CurrentState()
+	u.history[u.stateIndex].undone = u.tb.revertEvents(from.events)
 	u.stateIndex--
-	u.RestoreState()
+	u.RestoreState(from)
 }
 
 func (u *Undo) Redo() {
 	if u.stateIndex == len(u.history)-1 {
 		return
 	}
+	from := u.CurrentState()
 	u.stateIndex++
-	u.RestoreState()
+	u.RestoreState(from)
+	to := u.CurrentState()
+	u.tb.replayEvents(to.events, to.undone)
 }
@@ -34,6 +34,18 @@ func TestUndoAppend(t *testing.T) {
 	assert.Equal(t, tb.InnerString(), "\n")
 }
 
+func TestPutStateUnchanged(t *testing.T) {
+	tb := TaskBoxWithUndo()
+	tb.undo.PutState()
+	assert.Equal(t, 1, len(tb.undo.history))
+	assert.False(t, tb.modified)
+	tb.AppendLine("[ ] Foo")
+	tb.undo.PutState()
+	tb.undo.PutState()
+	assert.Equal(t, 2, len(tb.undo.history))
+	assert.Equal(t, 1, tb.undo.stateIndex)
+}
+
 func TestUndo(t *testing.T) {
 	tb := &TaskBox{Lines: []string{"[ ] Foo", "[ ] Bar", "[x] Baz"}}
 	tb.undo = NewUndo(tb)
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+/*
+Workspace is a directory with task lists in many subdirectories.
+All *.md files with tasks are combined to one list where lines of
+each file follow file marker line:
+
+	<!-- taskbox: TODO.md -->
+	- [ ] foo
+	<!-- taskbox: docs/TODO.md -->
+	- [ ] bar
+
+Markers are never shown. Line belongs to the file of the nearest
+marker above it so moving lines over marker moves them to another
+file. On save every changed file is written back separately.
+*/
+
+const (
+	FileMarkerPrefix = "<!-- taskbox: "
+	FileMarkerSuffix = " -->"
+)
+
+var reFile = *regexp.MustCompile(`^<!\-\- taskbox: .+ \-\->$`)
+
+func MakeFileMarker(path string) string {
+	return FileMarkerPrefix + filepath.ToSlash(path) + FileMarkerSuffix
+}
+
+func ParseFileMarker(s string) string {
+	if lineTypeOf(s) != lineFile {
+		panic(fmt.Sprintf("Not a file marker: %s", s))
+	}
+	return filepath.FromSlash(
+		s[len(FileMarkerPrefix) : len(s)-len(FileMarkerSuffix)])
+}
+
+// Is there at least one task in file
+func hasTasks(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if lineTypeOf(scanner.Text()) == lineTask {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
+
+// Find task lists recursively. Hidden directories are skipped.
+// Paths are relative to root
+func FindTaskFiles(root string) ([]string, error) {
+	var files []string
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		name := info.Name()
+		if info.IsDir() {
+			if path != root && strings.HasPrefix(name, ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(name, ".md") {
+			return nil
+		}
+		ok, err := hasTasks(path)
+		if err != nil || !ok {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, rel)
+		return nil
+	})
+	sort.Strings(files)
+	return files, err
+}
+
+func (tb *TaskBox) LoadWorkspace(root string) error {
+	files, err := FindTaskFiles(root)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("No task lists in %s", root)
+	}
+	lines := make([]string, 0)
+	for _, rel := range files {
+		t := &TaskBox{}
+		if err := t.Load(filepath.Join(root, rel)); err != nil {
+			return err
+		}
+		lines = append(lines, MakeFileMarker(rel))
+		lines = append(lines, t.Lines...)
+	}
+	hasUndo := (tb.undo != nil)
+	tb.path = root
+	tb.Lines = lines
+	tb.ClearSelection()
+	tb.calculate()
+	tb.modified = false
+	if hasUndo {
+		tb.undo = NewUndo(tb) // New Clear Undo
+	}
+	return nil
+}
+
+// Split workspace lines by files
+func (tb *TaskBox) workspaceFiles() ([]string, [][]string) {
+	var files []string
+	var lines [][]string
+	for _, s := range tb.Lines {
+		if lineTypeOf(s) == lineFile {
+			files = append(files, ParseFileMarker(s))
+			lines = append(lines, make([]string, 0))
+			continue
+		}
+		if len(files) == 0 {
+			// Lines above the first marker go to the first file
+			files = append(files, "")
+			lines = append(lines, make([]string, 0))
+		}
+		lines[len(lines)-1] = append(lines[len(lines)-1], s)
+	}
+	if len(files) > 1 && files[0] == "" {
+		files = files[1:]
+		lines[1] = append(lines[0], lines[1]...)
+		lines = lines[1:]
+	}
+	return files, lines
+}
+
+// Write changed files of workspace
+func (tb *TaskBox) SaveWorkspace(root string) error {
+	files, lines := tb.workspaceFiles()
+	for i, rel := range files {
+		if rel == "" {
+			continue
+		}
+		path := filepath.Join(root, rel)
+		disk := &TaskBox{}
+		if err := disk.Load(path); err != nil {
+			return err
+		}
+		if !reflect.DeepEqual(disk.Lines, lines[i]) {
+			t := &TaskBox{Lines: lines[i]}
+			if err := t.Save(path); err != nil {
+				return err
+			}
+		}
+	}
+	tb.path = root
+	tb.modified = false
+	return nil
+}
+
+// Source file for each line. Nil if lines are not a workspace
+func (tb *TaskBox) LineSources() []string {
+	var sources []string
+	var source string
+	for i, s := range tb.Lines {
+		if lineTypeOf(s) == lineFile {
+			if sources == nil {
+				sources = make([]string, len(tb.Lines))
+			}
+			source = ParseFileMarker(s)
+		}
+		if sources != nil {
+			sources[i] = source
+		}
+	}
+	return sources
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
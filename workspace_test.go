@@ -0,0 +1,125 @@
+package main
+
+import (
+	"github.com/MakeNowJust/heredoc"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func WorkspaceFixture(t *testing.T) string {
+	root, err := ioutil.TempDir("", "workspace")
+	assert.Nil(t, err)
+	files := map[string]string{
+		"TODO.md":          "# Root\n- [ ] Foo\n",
+		"README.md":        "No tasks here\n",
+		"api/TODO.md":      "- [ ] Bar\n- [x] Baz\n",
+		"web/docs/TODO.md": "<!--\n- [x] Old\n-->\n- [ ] Qux\n",
+		".git/TODO.md":     "- [ ] Hidden\n",
+	}
+	for name, s := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		ioutil.WriteFile(path, []byte(s), 0644)
+	}
+	return root
+}
+
+func TestFindTaskFiles(t *testing.T) {
+	root := WorkspaceFixture(t)
+	defer os.RemoveAll(root)
+
+	files, err := FindTaskFiles(root)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		"TODO.md",
+		filepath.FromSlash("api/TODO.md"),
+		filepath.FromSlash("web/docs/TODO.md"),
+	}, files)
+}
+
+func TestLoadWorkspace(t *testing.T) {
+	root := WorkspaceFixture(t)
+	defer os.RemoveAll(root)
+
+	tb := &TaskBox{}
+	assert.Nil(t, tb.LoadWorkspace(root))
+	tb.h = 10
+	assert.Equal(t, root, tb.path)
+	assert.Equal(t, tb.InnerString(), heredoc.Doc(`
+		<!-- taskbox: TODO.md -->
+		# Root
+		- [ ] Foo
+		<!-- taskbox: api/TODO.md -->
+		- [ ] Bar
+		- [x] Baz
+		<!-- taskbox: web/docs/TODO.md -->
+		<!-- - [x] Old -->
+		- [ ] Qux
+	`))
+	assert.Equal(t, tb.String(), heredoc.Doc(`
+		> # Root
+		  - [ ] Foo
+		  - [ ] Bar
+		  - [x] Baz
+		  - [ ] Qux
+	`))
+	sources := tb.LineSources()
+	assert.Equal(t, "TODO.md", sources[2])
+	assert.Equal(t, filepath.FromSlash("web/docs/TODO.md"), sources[8])
+
+	empty, _ := ioutil.TempDir("", "workspace")
+	defer os.RemoveAll(empty)
+	assert.EqualError(t, tb.LoadWorkspace(empty), "No task lists in "+empty)
+}
+
+func TestSaveWorkspace(t *testing.T) {
+	root := WorkspaceFixture(t)
+	defer os.RemoveAll(root)
+
+	tb := &TaskBox{}
+	tb.LoadWorkspace(root)
+	tb.h = 10
+	tb.cursor = 1
+	tb.MoveLineDown()
+	tb.cursor = 4
+	tb.ToggleTask()
+	tb.Save(tb.path)
+	assert.False(t, tb.modified)
+
+	read := func(name string) string {
+		b, _ := ioutil.ReadFile(filepath.Join(root, filepath.FromSlash(name)))
+		return string(b)
+	}
+	assert.Equal(t, "# Root\n- [ ] Bar\n", read("TODO.md"))
+	assert.Equal(t, "- [ ] Foo\n- [x] Baz\n", read("api/TODO.md"))
+	assert.Equal(t, "- [x] Qux\n<!--\n- [x] Old\n-->\n",
+		read("web/docs/TODO.md"))
+
+	// Unchanged files are not rewritten
+	ioutil.WriteFile(filepath.Join(root, "TODO.md"),
+		[]byte("<!-- - [ ] Foo -->\n# Root\n- [ ] Bar\n"), 0644)
+	tb.LoadWorkspace(root)
+	tb.Save(tb.path)
+	assert.Equal(t, "<!-- - [ ] Foo -->\n# Root\n- [ ] Bar\n", read("TODO.md"))
+
+	// Plain lines are not saved to directory
+	tb = &TaskBox{Lines: []string{"- [ ] New"}, path: "new.md", modified: true}
+	assert.EqualError(t, tb.Save(root), root+" is a directory")
+	assert.Equal(t, "new.md", tb.path)
+	assert.True(t, tb.modified)
+}
+
+func TestWorkspaceLinesAboveMarker(t *testing.T) {
+	tb := &TaskBox{Lines: []string{
+		"- [ ] Foo",
+		MakeFileMarker("a.md"),
+		"- [ ] Bar",
+		MakeFileMarker("b.md"),
+	}}
+	files, lines := tb.workspaceFiles()
+	assert.Equal(t, []string{"a.md", "b.md"}, files)
+	assert.Equal(t, [][]string{{"- [ ] Foo", "- [ ] Bar"}, {}}, lines)
+}
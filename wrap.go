@@ -0,0 +1,54 @@
+package main
+
+/*
+Long lines are soft-wrapped at spaces to screen width. View line
+takes as many screen rows as it has wrapped rows so scroll and page
+math counts rows, not lines. Wrapping is off with "wrap off" in
+config file
+*/
+
+var wordWrap = true
+
+// Split s into rows not wider than width. Rows end with space they
+// are wrapped at so joined rows give s. Long words are cut
+func wrapLine(s string, width int) []string {
+	r := []rune(s)
+	if width <= 0 || len(r) <= width {
+		return []string{s}
+	}
+	var rows []string
+	for len(r) > width {
+		cut := width
+		for i := width; i > 0; i-- {
+			if r[i-1] == ' ' {
+				cut = i
+				break
+			}
+		}
+		rows = append(rows, string(r[:cut]))
+		r = r[cut:]
+	}
+	return append(rows, string(r))
+}
+
+// Rows of line text as they fit editor width
+func (tb *TaskBox) wrap(s string) []string {
+	if !wordWrap {
+		return []string{s}
+	}
+	return wrapLine(s, tb.w-3)
+}
+
+// Screen rows of view line at pos
+func (tb *TaskBox) lineHeight(pos int) int {
+	return len(tb.wrap(tb.lineText(tb.view[pos])))
+}
+
+// Screen rows of view lines from..to-1
+func (tb *TaskBox) rowsBetween(from, to int) int {
+	n := 0
+	for pos := from; pos < to; pos++ {
+		n += tb.lineHeight(pos)
+	}
+	return n
+}
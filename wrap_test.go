@@ -0,0 +1,60 @@
+package main
+
+import (
+	"github.com/MakeNowJust/heredoc"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestWrapLine(t *testing.T) {
+	assert.Equal(t, []string{"foo bar"}, wrapLine("foo bar", 0))
+	assert.Equal(t, []string{"foo bar"}, wrapLine("foo bar", 7))
+	assert.Equal(t, []string{"foo ", "bar"}, wrapLine("foo bar", 6))
+	assert.Equal(t, []string{"foo ", "bar ", "baz"}, wrapLine("foo bar baz", 5))
+	assert.Equal(t, []string{"foobar", "baz"}, wrapLine("foobarbaz", 6))
+	assert.Equal(t, []string{"ёж ", "уж"}, wrapLine("ёж уж", 3))
+}
+
+func TestWrapView(t *testing.T) {
+	tb := &TaskBox{Lines: []string{
+		"- [ ] one",
+		"- [ ] two three four five",
+		"- [ ] six",
+		"- [ ] seven",
+	}}
+	tb.w = 15
+	tb.h = 4
+	tb.calculate()
+	assert.Equal(t, heredoc.Doc(`
+		> - [ ] one
+		  - [ ] two 
+		  three four 
+		  five
+	`), tb.String())
+
+	tb.CursorDown()
+	assert.Equal(t, 0, tb.scroll)
+	assert.Equal(t, 1, tb.CursorToY())
+	tb.CursorDown()
+	assert.Equal(t, 1, tb.scroll)
+	assert.Equal(t, 3, tb.CursorToY())
+	assert.Equal(t, heredoc.Doc(`
+		  - [ ] two 
+		  three four 
+		  five
+		> - [ ] six
+	`), tb.String())
+
+	tb.cursor = 0
+	tb.scrollToCursor()
+	tb.PageDown()
+	assert.Equal(t, 2, tb.cursor)
+	tb.PageUp()
+	assert.Equal(t, 0, tb.cursor)
+
+	wordWrap = false
+	defer func() { wordWrap = true }()
+	tb.PageDown()
+	assert.Equal(t, 3, tb.cursor)
+	assert.Equal(t, 0, tb.scroll)
+}